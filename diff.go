@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	workv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/encryption"
+	"github.com/vllry/cluster-reconciler/pkg/inventory"
+	"github.com/vllry/cluster-reconciler/pkg/kustomize"
+	"github.com/vllry/cluster-reconciler/pkg/reconcile"
+	"github.com/vllry/cluster-reconciler/pkg/sync"
+	"github.com/vllry/cluster-reconciler/pkg/types"
+)
+
+const (
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+	colorBold  = "\x1b[1m"
+	colorReset = "\x1b[0m"
+)
+
+// runDiff prints a unified diff between the manifests of a file, a directory or a hub work, and the live objects of a
+// cluster, without writing to the cluster. It returns whether any object drifted.
+func runDiff(args []string) (bool, error) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	dir := flags.String("dir", "", "The file or directory of the YAML or JSON manifests to compare the cluster with, or - to read them from stdin")
+	workName := flags.String("work", "", "The namespace/name of a work on the hub to compare the cluster with, instead of --dir")
+	hubKubeconfig := flags.String("hub-kubeconfig", "", "The kubeconfig of the hub, to read --work from")
+	kubeconfig := flags.String("kubeconfig", "", "The kubeconfig of the cluster. If it is empty, $KUBECONFIG, ~/.kube/config or the in-cluster config is used")
+	inventoryName := flags.String("inventory", "", "The inventory the manifests are synced with. If set, objects of the inventory which are not in the manifests are reported as pruned")
+	kustomizePath := flags.String("kustomize-path", kustomize.DefaultBinaryPath, "The kustomize binary used to build the kustomization of --work")
+	gitPath := flags.String("git-path", inventory.DefaultGitBinaryPath, "The git binary used to read the git source of --work")
+	decryptionKeySecret := flags.String("decryption-key-secret", "", "The namespace/name of the secret of the cluster holding the private key to decrypt the encrypted manifests of --work")
+	color := flags.String("color", "auto", "Colorize the diff, one of auto, always or never")
+	if err := flags.Parse(args); err != nil {
+		return false, err
+	}
+	if (*dir == "") == (*workName == "") {
+		return false, fmt.Errorf("exactly one of --dir or --work is required")
+	}
+
+	config, err := loadKubeconfig(*kubeconfig)
+	if err != nil {
+		return false, err
+	}
+	syncer, err := newSyncer(config, *inventoryName, nil)
+	if err != nil {
+		return false, err
+	}
+
+	var source inventory.Source = &inventory.DirectorySource{Path: *dir}
+	if *dir == "-" {
+		source = &inventory.ReaderSource{Reader: os.Stdin}
+	}
+	if *workName != "" {
+		namespace, name, err := cache.SplitMetaNamespaceKey(*workName)
+		if err != nil || namespace == "" {
+			return false, fmt.Errorf("invalid work %q, expected namespace/name", *workName)
+		}
+		hubConfig, err := loadKubeconfig(*hubKubeconfig)
+		if err != nil {
+			return false, err
+		}
+		hubClient, err := client.New(hubConfig, client.Options{Scheme: scheme})
+		if err != nil {
+			return false, fmt.Errorf("unable to create hub client: %v", err)
+		}
+		work := &workv1alpha1.Work{}
+		if err := hubClient.Get(context.Background(), k8stypes.NamespacedName{Namespace: namespace, Name: name}, work); err != nil {
+			return false, err
+		}
+
+		workSource := &inventory.WorkSource{
+			Work:      work,
+			Kustomize: kustomize.NewRenderer(*kustomizePath),
+			Git:       inventory.NewGitRepositories(*gitPath),
+		}
+		defer workSource.Git.Close()
+		if *decryptionKeySecret != "" {
+			namespace, name, err := cache.SplitMetaNamespaceKey(*decryptionKeySecret)
+			if err != nil || namespace == "" {
+				return false, fmt.Errorf("invalid decryption key secret %q, expected namespace/name", *decryptionKeySecret)
+			}
+			workSource.KeySource = encryption.NewKeySource(syncer.Client, namespace, name)
+		}
+		source = workSource
+	}
+
+	var desired map[types.ResourceIdentifier]types.Semistructured
+	fetch := inventory.FetchFunc(source, inventory.Resolver(syncer.RestMapper))
+	diffs, err := reconcile.DiffCluster(syncer.DynamicClient, func() (map[types.ResourceIdentifier]types.Semistructured, error) {
+		desired, err = fetch()
+		return desired, err
+	})
+	if err != nil {
+		return false, err
+	}
+	var pruned []reconcile.ReconcileResult
+	if *inventoryName != "" && sync.FetchFailed(desired) {
+		// An object which failed to be read may still be in the inventory, it would not be pruned.
+		fmt.Fprintln(os.Stderr, "Not reporting pruned objects, some objects of the inventory could not be read")
+	} else if *inventoryName != "" {
+		pruned, err = reconcile.PruneManagedResources(syncer.Client, syncer.DynamicClient, desired, *inventoryName, reconcile.ReconcileOptions{ReadOnly: true})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	colorize := *color == "always"
+	if *color == "auto" {
+		info, err := os.Stdout.Stat()
+		colorize = err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+	return printDiffs(os.Stdout, diffs, pruned, colorize), nil
+}
+
+// printDiffs prints the diff of every drifted object, and the objects which would be pruned.
+// It returns whether any object drifted or failed.
+func printDiffs(out io.Writer, diffs []reconcile.ObjectDiff, pruned []reconcile.ReconcileResult, colorize bool) bool {
+	paint := func(color, line string) string {
+		if !colorize {
+			return line
+		}
+		return color + line + colorReset
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return describeObject(diffs[i].Identifier) < describeObject(diffs[j].Identifier)
+	})
+	drifted := false
+	for _, diff := range diffs {
+		name := describeObject(diff.Identifier)
+		if diff.Err != nil {
+			drifted = true
+			fmt.Fprintln(out, paint(colorRed, fmt.Sprintf("%s: %v", name, diff.Err)))
+			continue
+		}
+		if !diff.Drifted {
+			continue
+		}
+		drifted = true
+		if diff.Sensitive || diff.Identifier.GroupVersionKind.Kind == "Secret" {
+			fmt.Fprintln(out, paint(colorBold, fmt.Sprintf("%s differs, its content is redacted", name)))
+			continue
+		}
+
+		live := ""
+		if diff.Actual != nil {
+			live = toYaml(reconcile.IntentOf(*diff.Actual, diff.Desired))
+		}
+		lines := reconcile.UnifiedDiff("live/"+name, "desired/"+name, live, toYaml(reconcile.IntentOf(diff.Desired, diff.Desired)))
+		if len(lines) == 0 {
+			// The objects only differ by fields which are not shown, such as the order of keys.
+			fmt.Fprintln(out, paint(colorBold, fmt.Sprintf("%s differs", name)))
+			continue
+		}
+		for i, line := range lines {
+			switch {
+			case i < 2:
+				line = paint(colorBold, line)
+			case line[0] == '@':
+				line = paint(colorCyan, line)
+			case line[0] == '-':
+				line = paint(colorRed, line)
+			case line[0] == '+':
+				line = paint(colorGreen, line)
+			}
+			fmt.Fprintln(out, line)
+		}
+	}
+
+	for _, result := range pruned {
+		drifted = true
+		if result.Err != nil {
+			fmt.Fprintln(out, paint(colorRed, fmt.Sprintf("%s: %v", describeObject(result.Identifier), result.Err)))
+			continue
+		}
+		fmt.Fprintln(out, paint(colorRed, fmt.Sprintf("%s is not in the manifests and would be pruned", describeObject(result.Identifier))))
+	}
+	return drifted
+}
+
+// describeObject names an object by its kind, namespace and name, or by its position if it could not be read.
+func describeObject(id types.ResourceIdentifier) string {
+	if id.GroupVersionKind.Kind == "" {
+		position := fmt.Sprintf("manifest %d", id.Ordinal)
+		if id.Source != "" {
+			position = fmt.Sprintf("%s %d", id.Source, id.Ordinal)
+		}
+		if id.Item > 0 {
+			position = fmt.Sprintf("%s, item %d", position, id.Item)
+		}
+		return position
+	}
+	if id.NamespacedName.Namespace == "" {
+		return fmt.Sprintf("%s/%s", id.GroupVersionKind.Kind, id.NamespacedName.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", id.GroupVersionKind.Kind, id.NamespacedName.Namespace, id.NamespacedName.Name)
+}
+
+func toYaml(obj unstructured.Unstructured) string {
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return fmt.Sprintf("<unprintable: %v>\n", err)
+	}
+	return string(data)
+}
@@ -7,9 +7,11 @@ require (
 	github.com/onsi/ginkgo v1.11.0
 	github.com/onsi/gomega v1.8.1
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.0.0
 	github.com/prometheus/common v0.4.1
 	k8s.io/api v0.18.0
 	k8s.io/apimachinery v0.18.0
 	k8s.io/client-go v0.18.0
 	sigs.k8s.io/controller-runtime v0.5.1-0.20200326092940-754026bd8510
+	sigs.k8s.io/yaml v1.2.0
 )
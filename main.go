@@ -2,22 +2,38 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"time"
+
+	"github.com/pkg/errors"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
 	cacheddiscovery "k8s.io/client-go/discovery/cached"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth" // Needed for misc auth.
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	workv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/clusterinfo"
 	"github.com/vllry/cluster-reconciler/pkg/controllers"
+	"github.com/vllry/cluster-reconciler/pkg/encryption"
+	"github.com/vllry/cluster-reconciler/pkg/heartbeat"
+	"github.com/vllry/cluster-reconciler/pkg/hubconfig"
+	"github.com/vllry/cluster-reconciler/pkg/inventory"
+	"github.com/vllry/cluster-reconciler/pkg/kustomize"
+	"github.com/vllry/cluster-reconciler/pkg/leaderelection"
 	"github.com/vllry/cluster-reconciler/pkg/restmapper"
+	"github.com/vllry/cluster-reconciler/pkg/signature"
 )
 
 var (
@@ -26,74 +42,416 @@ var (
 )
 
 func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
 	_ = workv1alpha1.AddToScheme(scheme)
 }
 
+// agentOptions configures the work pipeline of every spoke cluster.
+type agentOptions struct {
+	kustomizePath        string
+	gitPath              string
+	decryptionKeySecret  string
+	trustedKeysConfigMap string
+	reportOnly           bool
+}
+
+const (
+	// modeAgent applies the works of spoke clusters.
+	modeAgent = "agent"
+	// modeHub runs the hub controllers.
+	modeHub = "hub"
+)
+
 func main() {
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+	if len(os.Args) > 1 && os.Args[1] == "sync" {
+		if err := runSync(os.Args[2:]); err != nil {
+			setupLog.Error(err, "problem syncing")
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		// Like diff, the exit code is 1 if objects drifted, and 2 if the comparison failed.
+		drifted, err := runDiff(os.Args[2:])
+		if err != nil {
+			setupLog.Error(err, "problem diffing")
+			os.Exit(2)
+		}
+		if drifted {
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "status" {
+		if err := runStatus(os.Args[2:]); err != nil {
+			setupLog.Error(err, "problem reading the status of works")
+			os.Exit(1)
+		}
+		return
+	}
+
+	var mode string
+	flag.StringVar(&mode, "mode", modeAgent, "Run as the agent of spoke clusters, or as the hub controllers. One of agent or hub")
+
 	var metricsAddr string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 
 	var spokeKubeconfig string
 	flag.StringVar(&spokeKubeconfig, "spoke-kubeconfig", "", "The kubeconfig to connect to spoke cluster to apply resources")
+
+	var hubKubeconfig string
+	flag.StringVar(&hubKubeconfig, "hub-kubeconfig", "", "The kubeconfig to connect to hub cluster to fetch works. It is reloaded when it or the certificate, key and token files it references change. If it is empty, --kubeconfig or the in-cluster config is used")
+
+	var clusterNamespace string
+	flag.StringVar(&clusterNamespace, "cluster-namespace", "", "The hub namespace holding the works of the spoke cluster. Required unless --spoke-secrets-namespace is set")
+
+	var spokeSecretsNamespace string
+	flag.StringVar(&spokeSecretsNamespace, "spoke-secrets-namespace", "", "The namespace of secrets holding spoke kubeconfigs. If set, a pipeline runs for every secret instead of for --spoke-kubeconfig. Their kubeconfigs must hold inline credentials")
+
+	var spokeSecretsKubeconfig string
+	flag.StringVar(&spokeSecretsKubeconfig, "spoke-secrets-kubeconfig", "", "The kubeconfig to connect to the cluster holding the spoke secrets. The hub is used if it is empty")
+
+	opts := agentOptions{}
+	flag.StringVar(&opts.kustomizePath, "kustomize-path", kustomize.DefaultBinaryPath, "The kustomize binary used to build kustomizations in works")
+	flag.StringVar(&opts.gitPath, "git-path", inventory.DefaultGitBinaryPath, "The git binary used to read git sources of works")
+	flag.StringVar(&opts.decryptionKeySecret, "decryption-key-secret", "", "The namespace/name of the spoke secret holding the private key to decrypt encrypted manifests")
+	flag.StringVar(&opts.trustedKeysConfigMap, "trusted-keys-configmap", "", "The namespace/name of the spoke configmap holding the public keys trusted to sign works. Signatures are not verified if it is empty")
+	flag.BoolVar(&opts.reportOnly, "report-only", false, "Compare works with the spoke cluster and report drift, without writing to the spoke cluster")
+
+	var enableWebhooks bool
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Serve the admission webhooks for works")
+
+	var enableLeaderElection bool
+	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false, "Only reconcile works while holding a lease, so a single replica writes to the spoke cluster")
+	var leaderElectionCluster string
+	flag.StringVar(&leaderElectionCluster, "leader-election-cluster", "", "The cluster holding the lease, spoke or hub. It defaults to spoke for the agent of a single spoke cluster, and to hub otherwise")
+	leaderElectionConfig := leaderelection.Config{}
+	flag.StringVar(&leaderElectionConfig.Namespace, "leader-election-namespace", "cluster-reconciler-system", "The namespace of the lease")
+	flag.StringVar(&leaderElectionConfig.Name, "leader-election-id", leaderelection.DefaultName, "The name of the lease")
+	flag.DurationVar(&leaderElectionConfig.LeaseDuration, "leader-election-lease-duration", 15*time.Second, "The duration other replicas wait before taking over a lease which is not renewed")
+	flag.DurationVar(&leaderElectionConfig.RenewDeadline, "leader-election-renew-deadline", 10*time.Second, "The duration the leader retries renewing the lease before giving it up")
+	flag.DurationVar(&leaderElectionConfig.RetryPeriod, "leader-election-retry-period", 2*time.Second, "The duration between attempts to acquire or renew the lease")
 	flag.Parse()
 
-	config, err := clientcmd.BuildConfigFromFlags("", spokeKubeconfig)
-	if err != nil {
-		setupLog.Error(err, "Unable to get spoke kube config.")
+	var spokeConfig, secretsConfig *rest.Config
+	var err error
+	switch {
+	case mode == modeHub:
+	case mode != modeAgent:
+		setupLog.Info("--mode must be agent or hub.")
 		os.Exit(1)
+	case spokeSecretsNamespace != "":
+		if spokeSecretsKubeconfig != "" {
+			secretsConfig, err = clientcmd.BuildConfigFromFlags("", spokeSecretsKubeconfig)
+			if err != nil {
+				setupLog.Error(err, "Unable to get spoke secrets kube config.")
+				os.Exit(1)
+			}
+		}
+	default:
+		if clusterNamespace == "" {
+			setupLog.Info("--cluster-namespace is required.")
+			os.Exit(1)
+		}
+		spokeConfig, err = clientcmd.BuildConfigFromFlags("", spokeKubeconfig)
+		if err != nil {
+			setupLog.Error(err, "Unable to get spoke kube config.")
+			os.Exit(1)
+		}
+	}
+
+	run := func(hubConfig *rest.Config, stopCh <-chan struct{}) error {
+		if mode == modeHub {
+			return startHubManager(metricsAddr, hubConfig, enableWebhooks, stopCh)
+		}
+		if spokeSecretsNamespace == "" {
+			return startManager(metricsAddr, hubConfig, spokeConfig, clusterNamespace, opts, enableWebhooks, stopCh)
+		}
+		if secretsConfig == nil {
+			return startMultiClusterManager(metricsAddr, hubConfig, hubConfig, spokeSecretsNamespace, opts, enableWebhooks, stopCh)
+		}
+		return startMultiClusterManager(metricsAddr, hubConfig, secretsConfig, spokeSecretsNamespace, opts, enableWebhooks, stopCh)
+	}
+
+	if enableLeaderElection {
+		singleSpoke := mode == modeAgent && spokeSecretsNamespace == ""
+		switch leaderElectionCluster {
+		case "":
+			leaderElectionCluster = leaderElectionClusterHub
+			if singleSpoke {
+				leaderElectionCluster = leaderElectionClusterSpoke
+			}
+		case leaderElectionClusterHub:
+		case leaderElectionClusterSpoke:
+			if !singleSpoke {
+				setupLog.Info("--leader-election-cluster must be hub unless the agent runs for a single spoke cluster.")
+				os.Exit(1)
+			}
+		default:
+			setupLog.Info("--leader-election-cluster must be spoke or hub.")
+			os.Exit(1)
+		}
+		run = withLeaderElection(leaderElectionConfig, leaderElectionCluster, spokeConfig, run)
+	}
+
+	stopCh := ctrl.SetupSignalHandler()
+	if hubKubeconfig == "" {
+		err = run(ctrl.GetConfigOrDie(), stopCh)
+	} else {
+		// The manager is restarted when the hub credentials are rotated.
+		err = hubconfig.RunWithReload(setupLog, hubKubeconfig, stopCh, run)
 	}
-	client, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		setupLog.Error(err, "Unable to create spoke kube client.")
+		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
-	dynamicClient, err := dynamic.NewForConfig(config)
+}
+
+const (
+	leaderElectionClusterSpoke = "spoke"
+	leaderElectionClusterHub   = "hub"
+)
+
+// withLeaderElection only calls run while holding the lease in the spoke or hub cluster.
+// The lease is released when the manager is restarted with a new hub config.
+func withLeaderElection(config leaderelection.Config, cluster string, spokeConfig *rest.Config, run hubconfig.RunFunc) hubconfig.RunFunc {
+	return func(hubConfig *rest.Config, stopCh <-chan struct{}) error {
+		leaseConfig := hubConfig
+		if cluster == leaderElectionClusterSpoke {
+			leaseConfig = spokeConfig
+		}
+		client, err := kubernetes.NewForConfig(leaseConfig)
+		if err != nil {
+			return errors.Wrap(err, "unable to create leader election client")
+		}
+		config.Client = client
+
+		return leaderelection.Run(ctrl.Log.WithName("leaderelection"), config, stopCh, func(stopCh <-chan struct{}) error {
+			return run(hubConfig, stopCh)
+		})
+	}
+}
+
+func startManager(metricsAddr string, hubConfig *rest.Config, spokeConfig *rest.Config, clusterNamespace string, opts agentOptions, enableWebhooks bool, stopCh <-chan struct{}) error {
+
+	// The cache only holds the works of the cluster namespace.
+	mgr, err := newManager(hubConfig, ctrl.Options{Scheme: scheme, MetricsBindAddress: metricsAddr, Namespace: clusterNamespace})
 	if err != nil {
-		setupLog.Error(err, "Unable to create spoke dynamic client.")
-		os.Exit(1)
+		return errors.Wrap(err, "unable to start manager")
 	}
-	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+
+	// Add controller into manager
+	workReconciler, err := newWorkReconciler(mgr, "", spokeConfig, clusterNamespace, opts, stopCh)
 	if err != nil {
-		setupLog.Error(err, "Unable to create spoke dynamic client.")
-		os.Exit(1)
+		return err
+	}
+
+	if err = workReconciler.SetupWithManager(mgr); err != nil {
+		return errors.Wrap(err, "unable to create controller Work")
+	}
+
+	if err = addHubReporters(mgr, hubConfig, workReconciler.SpokeKubeClient, clusterNamespace); err != nil {
+		return err
+	}
+
+	if enableWebhooks {
+		if err = (&workv1alpha1.Work{}).SetupWebhookWithManager(mgr); err != nil {
+			return errors.Wrap(err, "unable to create webhook Work")
+		}
+	}
+
+	setupLog.Info("starting manager")
+	return mgr.Start(stopCh)
+}
+
+// startHubManager runs the hub controllers, which watch the heartbeats of the agents in cluster namespaces,
+// and place the works of work templates in cluster namespaces.
+func startHubManager(metricsAddr string, hubConfig *rest.Config, enableWebhooks bool, stopCh <-chan struct{}) error {
+	mgr, err := newManager(hubConfig, ctrl.Options{Scheme: scheme, MetricsBindAddress: metricsAddr})
+	if err != nil {
+		return errors.Wrap(err, "unable to start manager")
+	}
+
+	if err = (&controllers.ClusterHeartbeatReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("ClusterHeartbeat"),
+	}).SetupWithManager(mgr); err != nil {
+		return errors.Wrap(err, "unable to create controller ClusterHeartbeat")
+	}
+
+	if err = (&controllers.WorkTemplateReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("WorkTemplate"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		return errors.Wrap(err, "unable to create controller WorkTemplate")
+	}
+
+	if enableWebhooks {
+		if err = (&workv1alpha1.Work{}).SetupWebhookWithManager(mgr); err != nil {
+			return errors.Wrap(err, "unable to create webhook Work")
+		}
 	}
 
-	startManager(metricsAddr, client, dynamicClient, discoveryClient)
+	setupLog.Info("starting manager")
+	return mgr.Start(stopCh)
 }
 
-func startManager(metricsAddr string, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, discoveryClient discovery.DiscoveryInterface) {
+// addHubReporters renews the heartbeat lease of the agent, and publishes the facts of the spoke cluster
+// in workNamespace on the hub while mgr runs.
+func addHubReporters(mgr ctrl.Manager, hubConfig *rest.Config, spokeClient kubernetes.Interface, workNamespace string) error {
+	client, err := kubernetes.NewForConfig(hubConfig)
+	if err != nil {
+		return fmt.Errorf("unable to create hub kube client: %v", err)
+	}
+	holder, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	err = mgr.Add(&heartbeat.Renewer{
+		Client:        client,
+		Log:           ctrl.Log.WithName("heartbeat"),
+		Namespace:     workNamespace,
+		Holder:        holder,
+		LeaseDuration: heartbeat.DefaultLeaseDuration,
+		RenewInterval: heartbeat.DefaultRenewInterval,
+	})
+	if err != nil {
+		return err
+	}
+	return mgr.Add(&clusterinfo.Reporter{
+		HubClient:       mgr.GetClient(),
+		SpokeKubeClient: spokeClient,
+		Log:             ctrl.Log.WithName("clusterinfo"),
+		Namespace:       workNamespace,
+		Interval:        clusterinfo.DefaultInterval,
+	})
+}
+
+// startMultiClusterManager runs a work pipeline for every spoke kubeconfig secret in secretsNamespace.
+func startMultiClusterManager(metricsAddr string, hubConfig *rest.Config, secretsConfig *rest.Config, secretsNamespace string, opts agentOptions, enableWebhooks bool, stopCh <-chan struct{}) error {
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{Scheme: scheme, MetricsBindAddress: metricsAddr})
+	mgr, err := newManager(secretsConfig, ctrl.Options{Scheme: scheme, MetricsBindAddress: metricsAddr, Namespace: secretsNamespace})
 	if err != nil {
-		setupLog.Error(err, "unable to start manager")
-		os.Exit(1)
+		return errors.Wrap(err, "unable to start manager")
 	}
 
-	stopCh := ctrl.SetupSignalHandler()
+	spokeSecretReconciler := &controllers.SpokeSecretReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("SpokeSecret"),
+		StartPipeline: func(cluster string, spokeConfig *rest.Config, workNamespace string, stopCh <-chan struct{}) error {
+			return runPipeline(hubConfig, cluster, spokeConfig, workNamespace, opts, stopCh)
+		},
+	}
+	if err = spokeSecretReconciler.SetupWithManager(mgr); err != nil {
+		return errors.Wrap(err, "unable to create controller SpokeSecret")
+	}
+	// Pipelines use the hub config, so they stop with the manager.
+	defer spokeSecretReconciler.StopPipelines()
+
+	if enableWebhooks {
+		if err = (&workv1alpha1.Work{}).SetupWebhookWithManager(mgr); err != nil {
+			return errors.Wrap(err, "unable to create webhook Work")
+		}
+	}
+
+	setupLog.Info("starting manager")
+	return mgr.Start(stopCh)
+}
+
+// newManager creates a manager, retrying while the metrics address is still held by a stopped manager.
+func newManager(config *rest.Config, options ctrl.Options) (ctrl.Manager, error) {
+	var mgr ctrl.Manager
+	var lastErr error
+	err := wait.PollImmediate(time.Second, 10*time.Second, func() (bool, error) {
+		mgr, lastErr = ctrl.NewManager(config, options)
+		return lastErr == nil, nil
+	})
+	if err != nil {
+		return nil, lastErr
+	}
+	return mgr, nil
+}
+
+// runPipeline applies the works in workNamespace on the hub to a spoke cluster, until stopCh is closed.
+// Each pipeline has its own manager, so its cache only holds the works of its namespace.
+func runPipeline(hubConfig *rest.Config, cluster string, spokeConfig *rest.Config, workNamespace string, opts agentOptions, stopCh <-chan struct{}) error {
+	mgr, err := ctrl.NewManager(hubConfig, ctrl.Options{Scheme: scheme, MetricsBindAddress: "0", Namespace: workNamespace})
+	if err != nil {
+		return err
+	}
+
+	workReconciler, err := newWorkReconciler(mgr, cluster, spokeConfig, workNamespace, opts, stopCh)
+	if err != nil {
+		return err
+	}
+	if err := workReconciler.SetupWithManager(mgr); err != nil {
+		return err
+	}
+	if err := addHubReporters(mgr, hubConfig, workReconciler.SpokeKubeClient, workNamespace); err != nil {
+		return err
+	}
+
+	return mgr.Start(stopCh)
+}
+
+// newWorkReconciler creates the reconciler applying the works in workNamespace to a spoke cluster.
+// The spoke rest mapper is refreshed until stopCh is closed.
+func newWorkReconciler(mgr ctrl.Manager, cluster string, spokeConfig *rest.Config, workNamespace string, opts agentOptions, stopCh <-chan struct{}) (*controllers.WorkReconciler, error) {
+	client, err := kubernetes.NewForConfig(spokeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create spoke kube client: %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(spokeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create spoke dynamic client: %v", err)
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(spokeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create spoke discovery client: %v", err)
+	}
+
+	var keySource *encryption.KeySource
+	if opts.decryptionKeySecret != "" {
+		namespace, name, err := cache.SplitMetaNamespaceKey(opts.decryptionKeySecret)
+		if err != nil || namespace == "" {
+			return nil, fmt.Errorf("invalid decryption key secret %q, expected namespace/name", opts.decryptionKeySecret)
+		}
+		keySource = encryption.NewKeySource(client, namespace, name)
+	}
+
+	var trustedKeys *signature.TrustedKeySource
+	if opts.trustedKeysConfigMap != "" {
+		namespace, name, err := cache.SplitMetaNamespaceKey(opts.trustedKeysConfigMap)
+		if err != nil || namespace == "" {
+			return nil, fmt.Errorf("invalid trusted keys configmap %q, expected namespace/name", opts.trustedKeysConfigMap)
+		}
+		trustedKeys = signature.NewTrustedKeySource(client, namespace, name)
+	}
 
 	cachedSpokeDiscoveryClient := cacheddiscovery.NewMemCacheClient(discoveryClient)
 	restMapper := restmapper.NewMapper(cachedSpokeDiscoveryClient)
 	go restMapper.Run(stopCh)
 
-	// Add controller into manager
-	workReconciler := &controllers.WorkReconciler{
+	log := ctrl.Log.WithName("controllers").WithName("Work")
+	if cluster != "" {
+		log = log.WithValues("cluster", cluster)
+	}
+
+	return &controllers.WorkReconciler{
 		Client:             mgr.GetClient(),
-		Log:                ctrl.Log.WithName("controllers").WithName("Work"),
+		Log:                log,
 		Scheme:             mgr.GetScheme(),
-		SpokeKubeClient:    kubeClient,
+		SpokeKubeClient:    client,
 		SpokeDynamicClient: dynamicClient,
 		RestMapper:         restMapper,
-	}
-
-	if err = workReconciler.SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Work")
-		os.Exit(1)
-	}
-
-	setupLog.Info("starting manager")
-	if err := mgr.Start(stopCh); err != nil {
-		setupLog.Error(err, "problem running manager")
-		os.Exit(1)
-	}
+		Kustomize:          kustomize.NewRenderer(opts.kustomizePath),
+		Git:                inventory.NewGitRepositories(opts.gitPath),
+		KeySource:          keySource,
+		TrustedKeys:        trustedKeys,
+		Recorder:           mgr.GetEventRecorderFor("work-controller"),
+		ReportOnly:         opts.reportOnly,
+		ClusterName:        cluster,
+	}, nil
 }
@@ -0,0 +1,78 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterInfoSpec defines the desired state of ClusterInfo
+type ClusterInfoSpec struct {
+}
+
+// ClusterInfoStatus represents the facts gathered by the agent on spoke cluster
+type ClusterInfoStatus struct {
+	// KubernetesVersion is the git version of the kube-apiserver of spoke cluster.
+	// +optional
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// NodeCount is the number of nodes of spoke cluster.
+	// +optional
+	NodeCount int `json:"nodeCount,omitempty"`
+
+	// Allocatable is the sum of the allocatable cpu and memory of the nodes of spoke cluster.
+	// +optional
+	Allocatable corev1.ResourceList `json:"allocatable,omitempty"`
+
+	// APIGroups lists the names of the API groups served by spoke cluster, besides the core group.
+	// +optional
+	APIGroups []string `json:"apiGroups,omitempty"`
+
+	// LastUpdateTime is the last time the facts were gathered.
+	// +optional
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.kubernetesVersion`
+// +kubebuilder:printcolumn:name="Nodes",type=integer,JSONPath=`.status.nodeCount`
+// +kubebuilder:printcolumn:name="Updated",type=date,JSONPath=`.status.lastUpdateTime`
+
+// ClusterInfo is the Schema for the clusterinfoes API
+// The agent publishes the facts of its spoke cluster in its cluster namespace on the hub.
+type ClusterInfo struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterInfoSpec   `json:"spec,omitempty"`
+	Status ClusterInfoStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterInfoList contains a list of ClusterInfo
+type ClusterInfoList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterInfo `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterInfo{}, &ClusterInfoList{})
+}
@@ -31,6 +31,59 @@ type WorkSpec struct {
 
 	// Workload represents the manifest workload to be deployed on spoke cluster
 	Workload WorkloadTemplate `json:"workload,omitempty"`
+
+	// Suspend stops the agent from writing the workload to the spoke cluster, while the state of
+	// the workload is still reported. Resources of a suspended work are left on the spoke cluster
+	// when it is deleted.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// ReportOnly compares the workload with the spoke cluster and reports whether each resource is
+	// in sync, without creating or updating resources.
+	// +optional
+	ReportOnly bool `json:"reportOnly,omitempty"`
+
+	// DryRun sends every create and update of the workload to the spoke cluster as a server-side
+	// dry run. The DryRun conditions report whether the workload would be applied, and nothing on
+	// the spoke cluster changes.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Defaults represents values which are expanded into every manifest. They are expanded on admission,
+	// and by the agent for signed works and for kinds whose scope is only known on the spoke cluster.
+	// Namespaced objects without a namespace are placed in the default namespace if it is not set.
+	// +optional
+	Defaults *WorkloadDefaults `json:"defaults,omitempty"`
+
+	// Signatures represents detached signatures over the canonical content of the workload.
+	// When the spoke cluster is configured with trusted keys, workloads without a valid signature
+	// are not applied. Signed works are not defaulted on admission, the signature covers the workload
+	// as written along with its defaults.
+	// +optional
+	Signatures []WorkloadSignature `json:"signatures,omitempty"`
+}
+
+// WorkloadDefaults represents defaults for the manifests in a workload
+type WorkloadDefaults struct {
+	// Namespace is set on namespaced manifests which have no namespace. The "default" namespace
+	// is used if it is empty.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Labels are added to every manifest. Labels set on a manifest take precedence.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// WorkloadSignature represents a detached ed25519 signature over a workload
+type WorkloadSignature struct {
+	// KeyID identifies the key in the trusted keys of the spoke cluster that verifies the signature.
+	// +required
+	KeyID string `json:"keyID"`
+
+	// Signature is the ed25519 signature.
+	// +required
+	Signature []byte `json:"signature"`
 }
 
 // WorkloadTemplate represents the manifest workload to be deployed on spoke cluster
@@ -38,6 +91,73 @@ type WorkloadTemplate struct {
 	// Manifests represents a list of kuberenetes resources to be deployed on the spoke cluster.
 	// +optional
 	Manifests []Manifest `json:"manifests,omitempty"`
+
+	// EncryptedManifests represents a list of kubernetes resources encrypted to the public key of
+	// the spoke cluster. They are decrypted by the agent, and ordered after manifests.
+	// +optional
+	EncryptedManifests []EncryptedManifest `json:"encryptedManifests,omitempty"`
+
+	// Kustomization represents a kustomization which is built on the spoke cluster. The rendered
+	// resources are deployed alongside manifests, and ordered after encrypted manifests.
+	// +optional
+	Kustomization *Kustomization `json:"kustomization,omitempty"`
+
+	// Git represents manifests read from a git repository by the agent. The resources are deployed
+	// alongside manifests, and ordered after the rendered kustomization.
+	// +optional
+	Git *GitSource `json:"git,omitempty"`
+}
+
+// GitSource represents the manifests under a path of a git repository at a ref
+type GitSource struct {
+	// URL is the https, ssh or scp-like URL of the repository, such as https://github.com/org/manifests.git.
+	// +required
+	URL string `json:"url"`
+
+	// Ref is the branch, tag or commit to read. The default branch is read if it is empty.
+	// Signed works must pin the full commit SHA.
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// Path is the slash separated directory holding the manifests in the repository, every YAML
+	// or JSON file under it is read. The root of the repository is read if it is empty.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// EncryptedManifest represents a resource to be deployed on spoke cluster, which only the spoke
+// cluster can decrypt
+type EncryptedManifest struct {
+	// EncryptedKey is the AES-256 data key, encrypted to the spoke public key with RSA-OAEP (SHA-256).
+	// +required
+	EncryptedKey []byte `json:"encryptedKey"`
+
+	// Ciphertext is the manifest encrypted with the data key using AES-GCM, prefixed with the nonce.
+	// +required
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Kustomization represents an in-memory kustomize file tree, holding bases and overlays
+type Kustomization struct {
+	// Path is the directory in the file tree containing the kustomization to build.
+	// The root of the file tree is built if it is empty.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Files represents the files of the kustomization, such as kustomization.yaml, resources and patches.
+	// +required
+	Files []KustomizationFile `json:"files"`
+}
+
+// KustomizationFile represents a file in a kustomization
+type KustomizationFile struct {
+	// Path is the slash separated path of the file, relative to the root of the file tree.
+	// +required
+	Path string `json:"path"`
+
+	// Content is the content of the file.
+	// +required
+	Content string `json:"content"`
 }
 
 // Manifest represents a resource to be deployed on spoke cluster
@@ -59,8 +179,23 @@ type WorkStatus struct {
 	// 3. Available represents workload in Work exists on the spoke cluster.
 	// 4. Degraded represents the current state of workload does not match the desired
 	// state for a certain period.
+	// 5. DryRun represents workload in Work would be applied successfully on spoke cluster,
+	// it replaces Applied when dryRun is set.
+	// 6. Suspended represents workload in Work is not written to spoke cluster.
+	// 7. InSync represents workload in Work matches spoke cluster, it is reported when the work is
+	// suspended or report only.
+	// The hub sets every condition to Unknown once the heartbeat of the agent has expired.
 	Conditions []StatusCondition `json:"conditions"`
 
+	// ObservedGeneration is the generation of the work which the conditions were last
+	// reported for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastSyncedCommit is the commit of the git source of the workload which was last read.
+	// +optional
+	LastSyncedCommit string `json:"lastSyncedCommit,omitempty"`
+
 	// ManifestConditions represents the conditions of each resource in work deployed on
 	// spoke cluster.
 	// +optional
@@ -69,10 +204,19 @@ type WorkStatus struct {
 
 // ResourceIdentifier provides the identifiers needed to interact with any arbitrary object.
 type ResourceIdentifier struct {
-	// Ordinal represents an index in manifests list, so the condition can still be linked
-	// to a manifest even thougth manifest cannot be parsed successfully.
+	// Ordinal represents an index in manifests list, or in the list of Source if it is set, so the condition
+	// can still be linked to a manifest even thougth manifest cannot be parsed successfully.
 	Ordinal int `json:"ordinal,omitempty"`
 
+	// Source is the part of the workload holding the manifest, one of encryptedManifests, kustomization or git.
+	// It is empty for manifests.
+	// +optional
+	Source string `json:"source,omitempty"`
+
+	// Item is the index of the resource among the resources of the manifest, such as the items of a List.
+	// +optional
+	Item int `json:"item,omitempty"`
+
 	// Group is the group of the resource.
 	Group string `json:"group,omitempty"`
 
@@ -103,6 +247,11 @@ type ManifestCondition struct {
 	// Conditions represents the conditions of this resource on spoke cluster
 	// +required
 	Conditions []StatusCondition `json:"conditions"`
+
+	// Diff summarizes the fields changed by the last update of this resource on spoke cluster.
+	// Values of secrets are redacted, and the summary is truncated.
+	// +optional
+	Diff []string `json:"diff,omitempty"`
 }
 
 // StatusCondition contains condition information for a work.
@@ -0,0 +1,292 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/vllry/cluster-reconciler/pkg/parse"
+	"github.com/vllry/cluster-reconciler/pkg/types"
+)
+
+const (
+	// MaxManifestSize is the maximum size in bytes of a single manifest.
+	MaxManifestSize = 512 * 1024
+	// MaxWorkloadSize is the maximum size in bytes of all manifests in a work,
+	// which leaves room for status under the etcd object size limit.
+	MaxWorkloadSize = 1024 * 1024
+)
+
+// log is for logging in this package.
+var worklog = logf.Log.WithName("work-resource")
+
+func (r *Work) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-multicluster-x-k8s-io-v1alpha1-work,mutating=true,failurePolicy=fail,groups=multicluster.x-k8s.io,resources=works,verbs=create;update,versions=v1alpha1,name=mwork.kb.io
+
+var _ webhook.Defaulter = &Work{}
+
+// namespacedKinds are the built-in kinds which are namespaced. The hub cannot discover the spoke APIs,
+// so the namespace of other kinds is left unset and defaulted by the agent, which knows their scope.
+var namespacedKinds = map[schema.GroupKind]struct{}{
+	{Group: "", Kind: "ConfigMap"}:                            {},
+	{Group: "", Kind: "Endpoints"}:                            {},
+	{Group: "", Kind: "Event"}:                                {},
+	{Group: "", Kind: "LimitRange"}:                           {},
+	{Group: "", Kind: "PersistentVolumeClaim"}:                {},
+	{Group: "", Kind: "Pod"}:                                  {},
+	{Group: "", Kind: "PodTemplate"}:                          {},
+	{Group: "", Kind: "ReplicationController"}:                {},
+	{Group: "", Kind: "ResourceQuota"}:                        {},
+	{Group: "", Kind: "Secret"}:                               {},
+	{Group: "", Kind: "Service"}:                              {},
+	{Group: "", Kind: "ServiceAccount"}:                       {},
+	{Group: "apps", Kind: "ControllerRevision"}:               {},
+	{Group: "apps", Kind: "DaemonSet"}:                        {},
+	{Group: "apps", Kind: "Deployment"}:                       {},
+	{Group: "apps", Kind: "ReplicaSet"}:                       {},
+	{Group: "apps", Kind: "StatefulSet"}:                      {},
+	{Group: "autoscaling", Kind: "HorizontalPodAutoscaler"}:   {},
+	{Group: "batch", Kind: "CronJob"}:                         {},
+	{Group: "batch", Kind: "Job"}:                             {},
+	{Group: "coordination.k8s.io", Kind: "Lease"}:             {},
+	{Group: "discovery.k8s.io", Kind: "EndpointSlice"}:        {},
+	{Group: "extensions", Kind: "Ingress"}:                    {},
+	{Group: "networking.k8s.io", Kind: "Ingress"}:             {},
+	{Group: "networking.k8s.io", Kind: "NetworkPolicy"}:       {},
+	{Group: "policy", Kind: "PodDisruptionBudget"}:            {},
+	{Group: "rbac.authorization.k8s.io", Kind: "Role"}:        {},
+	{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}: {},
+}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Work) Default() {
+	worklog.Info("default", "name", r.Name)
+	DefaultWorkSpec(&r.Spec)
+}
+
+// DefaultWorkSpec sets the namespace and the managed annotation on every object of the manifests, and expands
+// the defaults of the spec into them if it has any. Manifests keep their shape, the items of a List are
+// defaulted in place. Signed works are left unchanged, since the signed workload is the one before defaulting,
+// the agent defaults them once the signature is verified.
+func DefaultWorkSpec(spec *WorkSpec) {
+	if len(spec.Signatures) > 0 {
+		return
+	}
+	defaults := WorkloadDefaults{}
+	if spec.Defaults != nil {
+		defaults = *spec.Defaults
+	}
+
+	for index := range spec.Workload.Manifests {
+		manifest := &spec.Workload.Manifests[index]
+		var value interface{}
+		if err := json.Unmarshal(manifest.Raw, &value); err != nil {
+			// Unparsable manifests are rejected by validation.
+			continue
+		}
+		if !defaultValue(value, defaults) {
+			continue
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		manifest.Raw = raw
+		manifest.Object = nil
+	}
+}
+
+// defaultValue defaults the objects of a manifest in place, which is an object, a List or an array of them.
+// It returns false if the value holds no object.
+func defaultValue(value interface{}, defaults WorkloadDefaults) bool {
+	switch v := value.(type) {
+	case []interface{}:
+		defaulted := false
+		for _, item := range v {
+			defaulted = defaultValue(item, defaults) || defaulted
+		}
+		return defaulted
+	case map[string]interface{}:
+		if items, isList := v["items"].([]interface{}); isList {
+			return defaultValue(items, defaults)
+		}
+		DefaultObject(&unstructured.Unstructured{Object: v}, defaults, func(gvk schema.GroupVersionKind) bool {
+			_, found := namespacedKinds[gvk.GroupKind()]
+			return found
+		})
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultObject sets the namespace, labels and the managed annotation on the object.
+// The namespace is only set if namespaced reports the kind of the object as namespaced.
+func DefaultObject(obj *unstructured.Unstructured, defaults WorkloadDefaults, namespaced func(schema.GroupVersionKind) bool) {
+	if obj.GetNamespace() == "" && namespaced(obj.GroupVersionKind()) {
+		namespace := defaults.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		obj.SetNamespace(namespace)
+	}
+
+	if len(defaults.Labels) > 0 {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		for key, value := range defaults.Labels {
+			if _, found := labels[key]; !found {
+				labels[key] = value
+			}
+		}
+		obj.SetLabels(labels)
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[types.ManagedAnnotationKey] = types.ManagedAnnotationValue
+	obj.SetAnnotations(annotations)
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-multicluster-x-k8s-io-v1alpha1-work,mutating=false,failurePolicy=fail,groups=multicluster.x-k8s.io,resources=works,versions=v1alpha1,name=vwork.kb.io
+
+var _ webhook.Validator = &Work{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Work) ValidateCreate() error {
+	worklog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Work) ValidateUpdate(old runtime.Object) error {
+	worklog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Work) ValidateDelete() error {
+	return nil
+}
+
+func (r *Work) validate() error {
+	allErrs := ValidateWorkload(r.Spec.Workload, field.NewPath("spec", "workload"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Work").GroupKind(), r.Name, allErrs)
+}
+
+// ValidateWorkload checks that every manifest in the workload parses to complete objects, the items of a List
+// being checked one by one, that no object is duplicated, and that the manifests are within the size limits.
+// Encrypted manifests can only be checked on the spoke cluster.
+func ValidateWorkload(workload WorkloadTemplate, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	manifestsPath := fldPath.Child("manifests")
+
+	total := 0
+	// Objects are duplicated if they share group, kind, namespace and name, even in different versions.
+	type objectKey struct {
+		groupKind      schema.GroupKind
+		namespacedName ktypes.NamespacedName
+	}
+	seen := map[objectKey]int{}
+	for index, manifest := range workload.Manifests {
+		path := manifestsPath.Index(index)
+		total += len(manifest.Raw)
+		if len(manifest.Raw) > MaxManifestSize {
+			allErrs = append(allErrs, field.TooLong(path, fmt.Sprintf("%d bytes", len(manifest.Raw)), MaxManifestSize))
+			continue
+		}
+
+		objs, err := parse.Parse(bytes.NewReader(manifest.Raw))
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(path, "", fmt.Sprintf("failed to parse manifest: %v", err)))
+			continue
+		}
+		for _, obj := range objs {
+			semi, err := types.UnstructuredToSemistructured(obj)
+			if err != nil {
+				allErrs = append(allErrs, field.Invalid(path, "", err.Error()))
+				continue
+			}
+
+			key := objectKey{groupKind: semi.Identifier.GroupVersionKind.GroupKind(), namespacedName: semi.Identifier.NamespacedName}
+			if first, found := seen[key]; found {
+				allErrs = append(allErrs, field.Duplicate(path, fmt.Sprintf("%s %s, also in manifest %d", semi.Identifier.GroupVersionKind.Kind, semi.Identifier.NamespacedName, first)))
+				continue
+			}
+			seen[key] = index
+		}
+	}
+
+	for _, manifest := range workload.EncryptedManifests {
+		total += len(manifest.EncryptedKey) + len(manifest.Ciphertext)
+	}
+	if workload.Kustomization != nil {
+		for _, file := range workload.Kustomization.Files {
+			total += len(file.Content)
+		}
+	}
+	if workload.Git != nil {
+		if workload.Git.URL == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("git", "url"), "the url of the repository is required"))
+		} else if err := ValidateGitURL(workload.Git.URL); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("git", "url"), workload.Git.URL, err.Error()))
+		}
+	}
+	if total > MaxWorkloadSize {
+		allErrs = append(allErrs, field.TooLong(fldPath, fmt.Sprintf("%d bytes", total), MaxWorkloadSize))
+	}
+
+	return allErrs
+}
+
+// scpLikeGitURL matches the scp-like syntax of ssh git URLs, such as git@github.com:org/repo.git.
+var scpLikeGitURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^/]`)
+
+// ValidateGitURL checks that the URL of a git source is a remote https or ssh URL. Other transports, such as
+// file:// or local paths, would let a work read the filesystem of the agent.
+func ValidateGitURL(url string) error {
+	if strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "ssh://") || scpLikeGitURL.MatchString(url) {
+		return nil
+	}
+	return fmt.Errorf("only https, ssh and scp-like ssh urls are allowed")
+}
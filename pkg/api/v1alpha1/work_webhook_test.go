@@ -0,0 +1,140 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func manifest(raw string) Manifest {
+	return Manifest{RawExtension: runtime.RawExtension{Raw: []byte(raw)}}
+}
+
+func TestValidateWorkload(t *testing.T) {
+	cm := `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"default"}}`
+	list := func(items ...string) Manifest {
+		return manifest(`{"apiVersion":"v1","kind":"List","items":[` + strings.Join(items, ",") + `]}`)
+	}
+	cases := []struct {
+		name      string
+		manifests []Manifest
+		errType   field.ErrorType
+	}{
+		{name: "valid", manifests: []Manifest{manifest(cm)}},
+		{name: "unparsable", manifests: []Manifest{manifest(`{"apiVersion":`)}, errType: field.ErrorTypeInvalid},
+		{name: "missing kind", manifests: []Manifest{manifest(`{"apiVersion":"v1","metadata":{"name":"cm"}}`)}, errType: field.ErrorTypeInvalid},
+		{name: "missing name", manifests: []Manifest{manifest(`{"apiVersion":"v1","kind":"ConfigMap"}`)}, errType: field.ErrorTypeInvalid},
+		{name: "duplicated", manifests: []Manifest{manifest(cm), manifest(cm)}, errType: field.ErrorTypeDuplicate},
+		{name: "list", manifests: []Manifest{list(cm, `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"s"}}`)}},
+		{name: "list item missing name", manifests: []Manifest{list(cm, `{"apiVersion":"v1","kind":"Secret"}`)}, errType: field.ErrorTypeInvalid},
+		{name: "duplicated in list", manifests: []Manifest{manifest(cm), list(cm)}, errType: field.ErrorTypeDuplicate},
+		{name: "too large", manifests: []Manifest{manifest(`{"data":"` + strings.Repeat("a", MaxManifestSize) + `"}`)}, errType: field.ErrorTypeTooLong},
+	}
+
+	for _, c := range cases {
+		errs := ValidateWorkload(WorkloadTemplate{Manifests: c.manifests}, field.NewPath("spec", "workload"))
+		if c.errType == "" {
+			if len(errs) != 0 {
+				t.Errorf("%s: expected no errors, got %v", c.name, errs)
+			}
+			continue
+		}
+		if len(errs) != 1 || errs[0].Type != c.errType {
+			t.Errorf("%s: expected one %s error, got %v", c.name, c.errType, errs)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	work := &Work{
+		Spec: WorkSpec{
+			Workload: WorkloadTemplate{
+				Manifests: []Manifest{
+					manifest(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","labels":{"team":"own"}}}`),
+					manifest(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"ns"}}`),
+					manifest(`{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"w"}}`),
+					manifest(`{"apiVersion":"v1","kind":"List","items":[{"apiVersion":"v1","kind":"Secret","metadata":{"name":"s"}},{"apiVersion":"v1","kind":"Service","metadata":{"name":"svc","namespace":"web"}}]}`),
+				},
+			},
+			Defaults: &WorkloadDefaults{
+				Namespace: "apps",
+				Labels:    map[string]string{"team": "platform", "env": "prod"},
+			},
+		},
+	}
+	work.Default()
+
+	expected := []string{
+		`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"annotations":{"cluster-reconciler-managed":"true"},"labels":{"env":"prod","team":"own"},"name":"cm","namespace":"apps"}}`,
+		`{"apiVersion":"v1","kind":"Namespace","metadata":{"annotations":{"cluster-reconciler-managed":"true"},"labels":{"env":"prod","team":"platform"},"name":"ns"}}`,
+		// The scope of unknown kinds is resolved by the agent.
+		`{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"annotations":{"cluster-reconciler-managed":"true"},"labels":{"env":"prod","team":"platform"},"name":"w"}}`,
+		// Items of lists are defaulted one by one.
+		`{"apiVersion":"v1","items":[` +
+			`{"apiVersion":"v1","kind":"Secret","metadata":{"annotations":{"cluster-reconciler-managed":"true"},"labels":{"env":"prod","team":"platform"},"name":"s","namespace":"apps"}},` +
+			`{"apiVersion":"v1","kind":"Service","metadata":{"annotations":{"cluster-reconciler-managed":"true"},"labels":{"env":"prod","team":"platform"},"name":"svc","namespace":"web"}}` +
+			`],"kind":"List"}`,
+	}
+	for index, manifest := range work.Spec.Workload.Manifests {
+		if strings.TrimSpace(string(manifest.Raw)) != expected[index] {
+			t.Errorf("manifest %d: expected %s, got %s", index, expected[index], manifest.Raw)
+		}
+	}
+}
+
+func TestDefaultWithoutDefaults(t *testing.T) {
+	work := &Work{
+		Spec: WorkSpec{
+			Workload: WorkloadTemplate{
+				Manifests: []Manifest{
+					manifest(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"}}`),
+					manifest(`{"apiVersion":"v1","kind":"List","items":[{"apiVersion":"v1","kind":"Secret","metadata":{"name":"s"}}]}`),
+				},
+			},
+		},
+	}
+	work.Default()
+
+	expected := []string{
+		`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"annotations":{"cluster-reconciler-managed":"true"},"name":"cm","namespace":"default"}}`,
+		// Lists keep their shape, even with a single item.
+		`{"apiVersion":"v1","items":[{"apiVersion":"v1","kind":"Secret","metadata":{"annotations":{"cluster-reconciler-managed":"true"},"name":"s","namespace":"default"}}],"kind":"List"}`,
+	}
+	for index, manifest := range work.Spec.Workload.Manifests {
+		if string(manifest.Raw) != expected[index] {
+			t.Errorf("manifest %d: expected %s, got %s", index, expected[index], manifest.Raw)
+		}
+	}
+}
+
+func TestDefaultSignedUnchanged(t *testing.T) {
+	raw := `{"kind":"ConfigMap","apiVersion":"v1","metadata":{"name":"cm"}}`
+	work := &Work{Spec: WorkSpec{
+		Defaults:   &WorkloadDefaults{Namespace: "apps"},
+		Signatures: []WorkloadSignature{{KeyID: "release", Signature: []byte("signature")}},
+	}}
+	work.Spec.Workload.Manifests = []Manifest{manifest(raw)}
+	work.Default()
+	if string(work.Spec.Workload.Manifests[0].Raw) != raw {
+		t.Errorf("expected the signed manifest to be unchanged, got %s", work.Spec.Workload.Manifests[0].Raw)
+	}
+}
+
+func TestValidateGitURL(t *testing.T) {
+	cases := map[string]bool{
+		"https://github.com/org/repo.git": true,
+		"ssh://git@github.com/org/repo":   true,
+		"git@github.com:org/repo.git":     true,
+		"file:///etc":                     false,
+		"/var/lib/repo":                   false,
+		"http://example.com/repo.git":     false,
+		"ext::sh -c touch% /tmp/pwned":    false,
+	}
+	for url, valid := range cases {
+		if err := ValidateGitURL(url); (err == nil) != valid {
+			t.Errorf("%s: expected valid %t, got %v", url, valid, err)
+		}
+	}
+}
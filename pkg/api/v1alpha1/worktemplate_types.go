@@ -0,0 +1,203 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WorkTemplateLabel is set on every work created from a work template, to the name of the template.
+const WorkTemplateLabel = "multicluster.x-k8s.io/work-template"
+
+// WorkTemplateGenerationAnnotation is set on every work created from a work template, to the generation
+// of the template the work was last updated to.
+const WorkTemplateGenerationAnnotation = "multicluster.x-k8s.io/work-template-generation"
+
+// WorkTemplateSpec defines the desired state of WorkTemplate
+type WorkTemplateSpec struct {
+	// ClusterSelector selects the clusters to place the work on, by the labels of their cluster
+	// namespace on the hub. A cluster namespace holds the ClusterInfo of its agent, whose labels are
+	// not used since the agent writes it. No cluster is selected if it is not set.
+	// +optional
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+
+	// Template is the spec of the work created in the namespace of every selected cluster.
+	Template WorkSpec `json:"template"`
+
+	// Rollout rolls a change of the template out to the selected clusters in batches.
+	// The change is placed on every selected cluster at once if it is not set.
+	// +optional
+	Rollout *RolloutStrategy `json:"rollout,omitempty"`
+}
+
+// RolloutStrategy places a change of the template on the canary clusters first, then on batches
+// of the other clusters. The next batch is placed once every work of the previous batches reports
+// the required conditions as True for the new generation, and the rollout pauses while any of them
+// fails.
+type RolloutStrategy struct {
+	// CanarySelector selects the clusters of the first batch, among the selected clusters.
+	// +optional
+	CanarySelector *metav1.LabelSelector `json:"canarySelector,omitempty"`
+
+	// BatchPercent is the share of the selected clusters placed in every batch after the canaries.
+	// A batch holds at least one cluster. Every cluster is in a single batch if it is not set.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	BatchPercent int32 `json:"batchPercent,omitempty"`
+
+	// RequiredConditions are the work conditions which must be True before the next batch is placed,
+	// such as Applied and Available. It defaults to Applied.
+	// +optional
+	RequiredConditions []string `json:"requiredConditions,omitempty"`
+}
+
+// RolloutPhase is the phase of a rollout.
+type RolloutPhase string
+
+const (
+	// RolloutProgressing means batches of the rollout are being placed.
+	RolloutProgressing RolloutPhase = "Progressing"
+	// RolloutPaused means a work of the rollout failed, no further batch is placed until it recovers.
+	RolloutPaused RolloutPhase = "Paused"
+	// RolloutComplete means every selected cluster reports the required conditions for the generation.
+	RolloutComplete RolloutPhase = "Complete"
+)
+
+// RolloutStatus represents the progress of a rollout.
+type RolloutStatus struct {
+	// Generation is the generation of the template being rolled out.
+	Generation int64 `json:"generation"`
+
+	// Phase is one of Progressing, Paused or Complete.
+	Phase RolloutPhase `json:"phase"`
+
+	// CurrentBatch is the index of the batch being placed, the canaries are batch 0 if any.
+	CurrentBatch int `json:"currentBatch"`
+
+	// Batches is the number of batches of the rollout.
+	Batches int `json:"batches"`
+
+	// UpdatedClusters is the number of clusters whose work was updated to the generation.
+	UpdatedClusters int `json:"updatedClusters"`
+
+	// Message is a human-readable message indicating details about the rollout.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// WorkTemplateStatus defines the observed state of WorkTemplate
+type WorkTemplateStatus struct {
+	// ObservedGeneration is the generation of the template which was last placed.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Clusters lists the namespaces of the clusters the work is placed on.
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+
+	// Rollout represents the progress of the last rollout, when the template has a rollout strategy.
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// Summary counts the works of the template by their conditions.
+	// +optional
+	Summary WorkTemplateSummary `json:"summary,omitempty"`
+
+	// Failures summarizes the failing works of the template, by cluster. The list is truncated,
+	// the count of failing works is in the summary.
+	// +optional
+	Failures []ClusterFailure `json:"failures,omitempty"`
+}
+
+// WorkTemplateSummary counts the works of a template by their conditions.
+type WorkTemplateSummary struct {
+	// Clusters is the number of clusters the work is placed on.
+	Clusters int `json:"clusters"`
+
+	// Applied is the number of works whose Applied condition is True.
+	Applied int `json:"applied"`
+
+	// Available is the number of works whose Available condition is True.
+	Available int `json:"available"`
+
+	// Degraded is the number of works whose Degraded condition is True.
+	Degraded int `json:"degraded"`
+
+	// Failed is the number of works whose Applied condition is False.
+	Failed int `json:"failed"`
+}
+
+// ClusterFailure summarizes why the work of a template fails on a cluster.
+type ClusterFailure struct {
+	// Cluster is the namespace of the cluster.
+	Cluster string `json:"cluster"`
+
+	// Reason is the reason of the failing condition of the work.
+	Reason string `json:"reason"`
+
+	// Message is the message of the failing condition of the work.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Manifests lists the failing manifests of the work. The list is truncated.
+	// +optional
+	Manifests []ManifestFailure `json:"manifests,omitempty"`
+}
+
+// ManifestFailure summarizes why a manifest fails on a cluster.
+type ManifestFailure struct {
+	// Identifier identifies the manifest in the work.
+	Identifier ResourceIdentifier `json:"identifier"`
+
+	// Message is the message of the failing condition of the manifest.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Clusters",type=integer,JSONPath=`.status.summary.clusters`
+// +kubebuilder:printcolumn:name="Applied",type=integer,JSONPath=`.status.summary.applied`
+// +kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.summary.failed`
+// +kubebuilder:printcolumn:name="Rollout",type=string,JSONPath=`.status.rollout.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// WorkTemplate is the Schema for the worktemplates API
+// A work is created from the template in the namespace of every selected cluster, and removed
+// from clusters which are no longer selected.
+type WorkTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WorkTemplateSpec   `json:"spec,omitempty"`
+	Status WorkTemplateStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WorkTemplateList contains a list of WorkTemplate
+type WorkTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WorkTemplate{}, &WorkTemplateList{})
+}
@@ -0,0 +1,120 @@
+package clusterinfo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/discover"
+)
+
+// Name is the name of the ClusterInfo published by the agent in its hub namespace.
+const Name = "cluster"
+
+// DefaultInterval is the interval between two gatherings of the facts of a spoke cluster.
+const DefaultInterval = time.Minute
+
+// Reporter periodically gathers the facts of a spoke cluster, and publishes them in a hub namespace.
+// It implements manager.Runnable, so the facts are only published while the manager runs.
+type Reporter struct {
+	// HubClient writes the ClusterInfo to the hub.
+	HubClient       client.Client
+	SpokeKubeClient kubernetes.Interface
+	Log             logr.Logger
+	Namespace       string
+	Interval        time.Duration
+}
+
+// +kubebuilder:rbac:groups=multicluster.x-k8s.io,resources=clusterinfoes,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=multicluster.x-k8s.io,resources=clusterinfoes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+
+// Start publishes the facts until stopCh is closed.
+func (r *Reporter) Start(stopCh <-chan struct{}) error {
+	wait.Until(func() {
+		if err := r.Report(); err != nil {
+			r.Log.Error(err, "unable to report cluster info", "namespace", r.Namespace)
+		}
+	}, r.Interval, stopCh)
+	return nil
+}
+
+// Report gathers the facts of the spoke cluster, and updates the status of the ClusterInfo.
+func (r *Reporter) Report() error {
+	status, err := Gather(r.SpokeKubeClient)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	info := &multiclusterv1alpha1.ClusterInfo{}
+	err = r.HubClient.Get(ctx, ktypes.NamespacedName{Namespace: r.Namespace, Name: Name}, info)
+	if apierrors.IsNotFound(err) {
+		info = &multiclusterv1alpha1.ClusterInfo{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: r.Namespace,
+				Name:      Name,
+			},
+		}
+		if err := r.HubClient.Create(ctx, info); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	info.Status = status
+	return r.HubClient.Status().Update(ctx, info)
+}
+
+// Gather returns the facts of a cluster.
+func Gather(client kubernetes.Interface) (multiclusterv1alpha1.ClusterInfoStatus, error) {
+	status := multiclusterv1alpha1.ClusterInfoStatus{}
+
+	version, err := client.Discovery().ServerVersion()
+	if err != nil {
+		return status, err
+	}
+	status.KubernetesVersion = version.GitVersion
+
+	nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return status, err
+	}
+	status.NodeCount = len(nodes.Items)
+	cpu := resource.Quantity{}
+	memory := resource.Quantity{}
+	for _, node := range nodes.Items {
+		cpu.Add(node.Status.Allocatable[corev1.ResourceCPU])
+		memory.Add(node.Status.Allocatable[corev1.ResourceMemory])
+	}
+	status.Allocatable = corev1.ResourceList{
+		corev1.ResourceCPU:    cpu,
+		corev1.ResourceMemory: memory,
+	}
+
+	apiVersions, err := discover.FetchApiVersions(client)
+	if err != nil {
+		return status, err
+	}
+	for group := range apiVersions {
+		if group != "" {
+			status.APIGroups = append(status.APIGroups, group)
+		}
+	}
+	sort.Strings(status.APIGroups)
+
+	status.LastUpdateTime = metav1.Now()
+	return status, nil
+}
@@ -0,0 +1,65 @@
+package clusterinfo
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func node(name, cpu, memory string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+		},
+	}
+}
+
+func TestGather(t *testing.T) {
+	client := fake.NewSimpleClientset(node("node1", "2", "4Gi"), node("node2", "500m", "1Gi"))
+	discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.FakedServerVersion = &version.Info{GitVersion: "v1.18.2"}
+	discovery.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Verbs: []string{"list"}}},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{{Name: "deployments", Verbs: []string{"list"}}},
+		},
+		{
+			GroupVersion: "batch/v1",
+			APIResources: []metav1.APIResource{{Name: "jobs", Verbs: []string{"list"}}},
+		},
+	}
+
+	status, err := Gather(client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.KubernetesVersion != "v1.18.2" {
+		t.Errorf("unexpected version %s", status.KubernetesVersion)
+	}
+	if status.NodeCount != 2 {
+		t.Errorf("unexpected node count %d", status.NodeCount)
+	}
+	if cpu := status.Allocatable[corev1.ResourceCPU]; cpu.Cmp(resource.MustParse("2500m")) != 0 {
+		t.Errorf("unexpected allocatable cpu %s", cpu.String())
+	}
+	if memory := status.Allocatable[corev1.ResourceMemory]; memory.Cmp(resource.MustParse("5Gi")) != 0 {
+		t.Errorf("unexpected allocatable memory %s", memory.String())
+	}
+	if !reflect.DeepEqual(status.APIGroups, []string{"apps", "batch"}) {
+		t.Errorf("unexpected api groups %v", status.APIGroups)
+	}
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/heartbeat"
+	"github.com/vllry/cluster-reconciler/pkg/helpers"
+)
+
+// ClusterHeartbeatReconciler runs on the hub, and marks the conditions of every work in a cluster namespace
+// as Unknown once the heartbeat lease of the agent has expired, or when the namespace has no heartbeat lease.
+// The agent overwrites the conditions when it reconciles the works again.
+type ClusterHeartbeatReconciler struct {
+	client.Client
+	Log logr.Logger
+}
+
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch
+
+func (r *ClusterHeartbeatReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("lease", req.NamespacedName)
+
+	// A lease which was deleted, or never created by the agent, is an expired heartbeat.
+	var renewTime metav1.MicroTime
+	lease := &coordinationv1.Lease{}
+	err := r.Get(ctx, req.NamespacedName, lease)
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return ctrl.Result{}, err
+	default:
+		if expiresIn := heartbeat.ExpiresIn(lease, time.Now()); expiresIn > 0 {
+			// Check again once the lease would expire, unless it is renewed before.
+			return ctrl.Result{RequeueAfter: expiresIn + time.Second}, nil
+		}
+		if lease.Spec.RenewTime != nil {
+			renewTime = *lease.Spec.RenewTime
+		}
+	}
+
+	works := &multiclusterv1alpha1.WorkList{}
+	if err := r.List(ctx, works, client.InNamespace(req.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+	for i := range works.Items {
+		work := &works.Items[i]
+		if !markConditionsUnknown(work, renewTime) {
+			continue
+		}
+		log.Info("cluster heartbeat expired, marking work conditions unknown", "work", work.Name)
+		if err := r.Status().Update(ctx, work); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// markConditionsUnknown sets every condition of the work to Unknown, and returns false if none changed.
+// A work which has no Applied condition yet, since the agent never reconciled it, is given an Unknown one.
+// renewTime is zero if the agent has no heartbeat lease.
+func markConditionsUnknown(work *multiclusterv1alpha1.Work, renewTime metav1.MicroTime) bool {
+	message := fmt.Sprintf("The agent has not renewed its heartbeat since %s", renewTime.Format(time.RFC3339))
+	if renewTime.IsZero() {
+		message = "The agent has no heartbeat lease in the cluster namespace"
+	}
+	conditionTypes := []string{}
+	for _, condition := range work.Status.Conditions {
+		conditionTypes = append(conditionTypes, condition.Type)
+	}
+	if helpers.FindWorkCondition(work.Status.Conditions, "Applied") == nil {
+		conditionTypes = append(conditionTypes, "Applied")
+	}
+
+	changed := false
+	for _, conditionType := range conditionTypes {
+		condition := helpers.FindWorkCondition(work.Status.Conditions, conditionType)
+		if condition != nil && condition.Status == metav1.ConditionUnknown && condition.Reason == "ClusterHeartbeatExpired" {
+			continue
+		}
+		helpers.SetWorkCondition(&work.Status.Conditions, multiclusterv1alpha1.StatusCondition{
+			Type:    conditionType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "ClusterHeartbeatExpired",
+			Message: message,
+		})
+		changed = true
+	}
+	return changed
+}
+
+func (r *ClusterHeartbeatReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isHeartbeatLease := func(meta metav1.Object) bool {
+		return meta.GetName() == heartbeat.LeaseName
+	}
+	// Created works check the lease of their namespace, which may never have been created by an agent.
+	isWork := func(obj runtime.Object) bool {
+		_, ok := obj.(*multiclusterv1alpha1.Work)
+		return ok
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&coordinationv1.Lease{}).
+		Watches(&source.Kind{Type: &multiclusterv1alpha1.Work{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(func(obj handler.MapObject) []ctrl.Request {
+				return []ctrl.Request{{NamespacedName: ktypes.NamespacedName{Namespace: obj.Meta.GetNamespace(), Name: heartbeat.LeaseName}}}
+			}),
+		}).
+		WithEventFilter(predicate.Funcs{
+			CreateFunc:  func(e event.CreateEvent) bool { return isHeartbeatLease(e.Meta) || isWork(e.Object) },
+			UpdateFunc:  func(e event.UpdateEvent) bool { return isHeartbeatLease(e.MetaNew) && !isWork(e.ObjectNew) },
+			DeleteFunc:  func(e event.DeleteEvent) bool { return isHeartbeatLease(e.Meta) && !isWork(e.Object) },
+			GenericFunc: func(e event.GenericEvent) bool { return isHeartbeatLease(e.Meta) && !isWork(e.Object) },
+		}).
+		Complete(r)
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/heartbeat"
+	"github.com/vllry/cluster-reconciler/pkg/helpers"
+)
+
+func TestHeartbeatExpired(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := multiclusterv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := coordinationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	renewTime := metav1.NewMicroTime(time.Now().Add(-time.Hour))
+	duration := int32(60)
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cluster", Name: heartbeat.LeaseName},
+		Spec:       coordinationv1.LeaseSpec{RenewTime: &renewTime, LeaseDurationSeconds: &duration},
+	}
+	applied := fakeWork("applied")
+	applied.Status.Conditions = []multiclusterv1alpha1.StatusCondition{{Type: "Applied", Status: metav1.ConditionTrue, Reason: "WorkAppliedSuccess"}}
+	// The agent never reconciled the new work.
+	created := fakeWork("created")
+
+	r := &ClusterHeartbeatReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme, lease, applied, created),
+		Log:    ctrl.Log.WithName("controllers").WithName("ClusterHeartbeat"),
+	}
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "cluster", Name: heartbeat.LeaseName}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"applied", "created"} {
+		work := &multiclusterv1alpha1.Work{}
+		if err := r.Get(context.Background(), types.NamespacedName{Namespace: "cluster", Name: name}, work); err != nil {
+			t.Fatal(err)
+		}
+		cond := helpers.FindWorkCondition(work.Status.Conditions, "Applied")
+		if cond == nil || cond.Status != metav1.ConditionUnknown || cond.Reason != "ClusterHeartbeatExpired" {
+			t.Errorf("%s: expected the Applied condition to be unknown, got %+v", name, cond)
+		}
+	}
+}
+
+func TestHeartbeatMissing(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := multiclusterv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := coordinationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	applied := fakeWork("applied")
+	applied.Status.Conditions = []multiclusterv1alpha1.StatusCondition{{Type: "Applied", Status: metav1.ConditionTrue, Reason: "WorkAppliedSuccess"}}
+
+	// The lease was deleted, or the agent never created it.
+	r := &ClusterHeartbeatReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme, applied),
+		Log:    ctrl.Log.WithName("controllers").WithName("ClusterHeartbeat"),
+	}
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "cluster", Name: heartbeat.LeaseName}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	work := &multiclusterv1alpha1.Work{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "cluster", Name: "applied"}, work); err != nil {
+		t.Fatal(err)
+	}
+	cond := helpers.FindWorkCondition(work.Status.Conditions, "Applied")
+	if cond == nil || cond.Status != metav1.ConditionUnknown || cond.Reason != "ClusterHeartbeatExpired" || !strings.Contains(cond.Message, "no heartbeat lease") {
+		t.Errorf("expected the Applied condition to be unknown, got %+v", cond)
+	}
+}
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+)
+
+var (
+	// driftedManifests counts the resources of a work which do not match its manifests.
+	driftedManifests = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "work_drifted_manifests",
+		Help: "Number of resources in a report only or suspended work which do not match the manifests",
+	}, []string{"namespace", "work"})
+
+	// inSyncManifests counts the resources of a work which match its manifests.
+	inSyncManifests = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "work_in_sync_manifests",
+		Help: "Number of resources in a report only or suspended work which match the manifests",
+	}, []string{"namespace", "work"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(driftedManifests, inSyncManifests)
+}
+
+// recordDrift records the drift counts of a work.
+func recordDrift(work *multiclusterv1alpha1.Work, drifted, inSync int) {
+	driftedManifests.WithLabelValues(work.Namespace, work.Name).Set(float64(drifted))
+	inSyncManifests.WithLabelValues(work.Namespace, work.Name).Set(float64(inSync))
+}
+
+// forgetDrift removes the drift counts of a work, once it is no longer observed.
+func forgetDrift(work *multiclusterv1alpha1.Work) {
+	driftedManifests.DeleteLabelValues(work.Namespace, work.Name)
+	inSyncManifests.DeleteLabelValues(work.Namespace, work.Name)
+}
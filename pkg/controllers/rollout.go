@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/helpers"
+)
+
+// defaultRequiredConditions are the work conditions gating a rollout, unless the strategy sets them.
+var defaultRequiredConditions = []string{"Applied"}
+
+// clusterRolloutState is the state of the work of a cluster in a rollout.
+type clusterRolloutState int
+
+const (
+	// clusterPending means the work is not updated to the generation of the rollout yet.
+	clusterPending clusterRolloutState = iota
+	// clusterWaiting means the work is updated, and the agent has not reported it as ready yet.
+	clusterWaiting
+	// clusterReady means the work reports every required condition as True.
+	clusterReady
+	// clusterFailed means the work reports a required condition as False, or is degraded.
+	clusterFailed
+)
+
+// planRollout returns the clusters to update to the generation of the template, and the progress of the rollout.
+// clusters is sorted, and works holds the work of the template in each cluster namespace, if any.
+func planRollout(template *multiclusterv1alpha1.WorkTemplate, clusters []string, canaries map[string]bool, works map[string]*multiclusterv1alpha1.Work) ([]string, *multiclusterv1alpha1.RolloutStatus) {
+	strategy := template.Spec.Rollout
+	required := strategy.RequiredConditions
+	if len(required) == 0 {
+		required = defaultRequiredConditions
+	}
+
+	batches := rolloutBatches(clusters, canaries, strategy.BatchPercent)
+	status := &multiclusterv1alpha1.RolloutStatus{
+		Generation: template.Generation,
+		Phase:      multiclusterv1alpha1.RolloutProgressing,
+		Batches:    len(batches),
+	}
+	for _, cluster := range clusters {
+		if workTemplateGeneration(works[cluster]) == template.Generation {
+			status.UpdatedClusters++
+		}
+	}
+
+	for i, batch := range batches {
+		status.CurrentBatch = i
+		var pending, waiting, failed []string
+		for _, cluster := range batch {
+			switch rolloutState(works[cluster], template.Generation, required) {
+			case clusterPending:
+				pending = append(pending, cluster)
+			case clusterWaiting:
+				waiting = append(waiting, cluster)
+			case clusterFailed:
+				failed = append(failed, cluster)
+			}
+		}
+
+		if len(failed) > 0 {
+			status.Phase = multiclusterv1alpha1.RolloutPaused
+			status.Message = fmt.Sprintf("Paused at batch %d of %d since the works of clusters %s failed", i+1, len(batches), strings.Join(failed, ", "))
+			return nil, status
+		}
+		if len(pending) > 0 {
+			status.Message = fmt.Sprintf("Placing batch %d of %d", i+1, len(batches))
+			return pending, status
+		}
+		if len(waiting) > 0 {
+			status.Message = fmt.Sprintf("Waiting for the works of clusters %s in batch %d of %d", strings.Join(waiting, ", "), i+1, len(batches))
+			return nil, status
+		}
+	}
+
+	status.Phase = multiclusterv1alpha1.RolloutComplete
+	status.Message = "The works of every selected cluster are ready"
+	return nil, status
+}
+
+// rolloutBatches splits the clusters into the batches of a rollout. The canaries are the first batch,
+// and the other clusters are split into batches of batchPercent of all clusters.
+func rolloutBatches(clusters []string, canaries map[string]bool, batchPercent int32) [][]string {
+	batches := [][]string{}
+	canaryBatch := []string{}
+	rest := []string{}
+	for _, cluster := range clusters {
+		if canaries[cluster] {
+			canaryBatch = append(canaryBatch, cluster)
+		} else {
+			rest = append(rest, cluster)
+		}
+	}
+	if len(canaryBatch) > 0 {
+		batches = append(batches, canaryBatch)
+	}
+
+	size := len(rest)
+	if batchPercent > 0 && batchPercent < 100 {
+		// Round up, so every batch holds at least one cluster.
+		size = (len(clusters)*int(batchPercent) + 99) / 100
+	}
+	for len(rest) > 0 {
+		if size > len(rest) {
+			size = len(rest)
+		}
+		batches = append(batches, rest[:size])
+		rest = rest[size:]
+	}
+	return batches
+}
+
+// rolloutState returns the state of a work in the rollout of a generation of its template.
+func rolloutState(work *multiclusterv1alpha1.Work, generation int64, required []string) clusterRolloutState {
+	if workTemplateGeneration(work) != generation {
+		return clusterPending
+	}
+	if work.Status.ObservedGeneration != work.Generation {
+		return clusterWaiting
+	}
+
+	if helpers.IsConditionTrue(helpers.FindWorkCondition(work.Status.Conditions, "Degraded")) {
+		return clusterFailed
+	}
+	state := clusterReady
+	for _, conditionType := range required {
+		condition := helpers.FindWorkCondition(work.Status.Conditions, conditionType)
+		if condition != nil && condition.Status == metav1.ConditionFalse {
+			return clusterFailed
+		}
+		if !helpers.IsConditionTrue(condition) {
+			state = clusterWaiting
+		}
+	}
+	return state
+}
+
+// workTemplateGeneration returns the generation of the template a work was last updated to, or -1.
+func workTemplateGeneration(work *multiclusterv1alpha1.Work) int64 {
+	if work == nil {
+		return -1
+	}
+	generation, err := strconv.ParseInt(work.Annotations[multiclusterv1alpha1.WorkTemplateGenerationAnnotation], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return generation
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+)
+
+func TestRolloutBatches(t *testing.T) {
+	clusters := []string{"a", "b", "c", "d", "e"}
+	batches := rolloutBatches(clusters, map[string]bool{"c": true}, 40)
+	expected := [][]string{{"c"}, {"a", "b"}, {"d", "e"}}
+	if !reflect.DeepEqual(batches, expected) {
+		t.Errorf("expected %v, got %v", expected, batches)
+	}
+
+	batches = rolloutBatches(clusters, nil, 0)
+	expected = [][]string{clusters}
+	if !reflect.DeepEqual(batches, expected) {
+		t.Errorf("expected %v, got %v", expected, batches)
+	}
+}
+
+func TestPlanRollout(t *testing.T) {
+	template := &multiclusterv1alpha1.WorkTemplate{
+		Spec: multiclusterv1alpha1.WorkTemplateSpec{
+			Rollout: &multiclusterv1alpha1.RolloutStrategy{BatchPercent: 50},
+		},
+	}
+	template.Generation = 2
+	clusters := []string{"a", "b", "c", "d"}
+	canaries := map[string]bool{"d": true}
+
+	// works returns the works of a template generation, with the status of their Applied condition.
+	works := func(generation string, applied map[string]metav1.ConditionStatus) map[string]*multiclusterv1alpha1.Work {
+		result := map[string]*multiclusterv1alpha1.Work{}
+		for cluster, status := range applied {
+			work := &multiclusterv1alpha1.Work{}
+			work.Annotations = map[string]string{multiclusterv1alpha1.WorkTemplateGenerationAnnotation: generation}
+			if status != "" {
+				work.Status.Conditions = []multiclusterv1alpha1.StatusCondition{{Type: "Applied", Status: status}}
+			}
+			result[cluster] = work
+		}
+		return result
+	}
+	applied, failed := metav1.ConditionTrue, metav1.ConditionFalse
+
+	cases := []struct {
+		name            string
+		works           map[string]*multiclusterv1alpha1.Work
+		expectedPlace   []string
+		expectedPhase   multiclusterv1alpha1.RolloutPhase
+		expectedBatch   int
+		expectedUpdated int
+	}{
+		{name: "canary is placed first", works: works("1", map[string]metav1.ConditionStatus{"a": applied}), expectedPlace: []string{"d"}, expectedPhase: multiclusterv1alpha1.RolloutProgressing},
+		{name: "waiting for canary", works: works("2", map[string]metav1.ConditionStatus{"d": ""}), expectedPhase: multiclusterv1alpha1.RolloutProgressing, expectedUpdated: 1},
+		{name: "next batch once canary is applied", works: works("2", map[string]metav1.ConditionStatus{"d": applied}), expectedPlace: []string{"a", "b"}, expectedPhase: multiclusterv1alpha1.RolloutProgressing, expectedBatch: 1, expectedUpdated: 1},
+		{name: "paused on failure", works: works("2", map[string]metav1.ConditionStatus{"d": applied, "a": failed, "b": applied}), expectedPhase: multiclusterv1alpha1.RolloutPaused, expectedBatch: 1, expectedUpdated: 3},
+		{name: "complete", works: works("2", map[string]metav1.ConditionStatus{"a": applied, "b": applied, "c": applied, "d": applied}), expectedPhase: multiclusterv1alpha1.RolloutComplete, expectedBatch: 2, expectedUpdated: 4},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			toPlace, status := planRollout(template, clusters, canaries, c.works)
+			if !reflect.DeepEqual(toPlace, c.expectedPlace) {
+				t.Errorf("expected to place %v, got %v", c.expectedPlace, toPlace)
+			}
+			if status.Phase != c.expectedPhase {
+				t.Errorf("expected phase %s, got %s: %s", c.expectedPhase, status.Phase, status.Message)
+			}
+			if status.CurrentBatch != c.expectedBatch {
+				t.Errorf("expected batch %d, got %d", c.expectedBatch, status.CurrentBatch)
+			}
+			if status.UpdatedClusters != c.expectedUpdated {
+				t.Errorf("expected %d updated clusters, got %d", c.expectedUpdated, status.UpdatedClusters)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/helpers"
+)
+
+const (
+	// MaxClusterFailures is the maximum number of clusters in the failures of a work template.
+	MaxClusterFailures = 20
+	// MaxManifestFailures is the maximum number of manifests in the failure of a cluster.
+	MaxManifestFailures = 10
+)
+
+// summarizeWorks counts the works of a template by their conditions, and summarizes the failing works.
+// clusters is sorted, and works holds the work of the template in each cluster namespace, if any.
+func summarizeWorks(clusters []string, works map[string]*multiclusterv1alpha1.Work) (multiclusterv1alpha1.WorkTemplateSummary, []multiclusterv1alpha1.ClusterFailure) {
+	summary := multiclusterv1alpha1.WorkTemplateSummary{}
+	failures := []multiclusterv1alpha1.ClusterFailure{}
+	for _, cluster := range clusters {
+		work := works[cluster]
+		if work == nil {
+			continue
+		}
+		summary.Clusters++
+
+		conditions := work.Status.Conditions
+		applied := helpers.FindWorkCondition(conditions, "Applied")
+		degraded := helpers.FindWorkCondition(conditions, "Degraded")
+		if helpers.IsConditionTrue(applied) {
+			summary.Applied++
+		}
+		if helpers.IsConditionTrue(helpers.FindWorkCondition(conditions, "Available")) {
+			summary.Available++
+		}
+		if helpers.IsConditionTrue(degraded) {
+			summary.Degraded++
+		}
+
+		var failing *multiclusterv1alpha1.StatusCondition
+		if applied != nil && applied.Status == metav1.ConditionFalse {
+			summary.Failed++
+			failing = applied
+		} else if helpers.IsConditionTrue(degraded) {
+			failing = degraded
+		}
+		if failing == nil || len(failures) >= MaxClusterFailures {
+			continue
+		}
+		failures = append(failures, multiclusterv1alpha1.ClusterFailure{
+			Cluster:   cluster,
+			Reason:    failing.Reason,
+			Message:   failing.Message,
+			Manifests: failingManifests(work.Status.ManifestConditions),
+		})
+	}
+	return summary, failures
+}
+
+// failingManifests returns the manifests with a False Applied or Available condition, or a True Degraded condition.
+func failingManifests(manifestConditions []multiclusterv1alpha1.ManifestCondition) []multiclusterv1alpha1.ManifestFailure {
+	failures := []multiclusterv1alpha1.ManifestFailure{}
+	for _, manifestCondition := range manifestConditions {
+		for _, condition := range manifestCondition.Conditions {
+			failed := condition.Status == metav1.ConditionFalse && (condition.Type == "Applied" || condition.Type == "Available")
+			if condition.Type == "Degraded" && condition.Status == metav1.ConditionTrue {
+				failed = true
+			}
+			if !failed {
+				continue
+			}
+			failures = append(failures, multiclusterv1alpha1.ManifestFailure{
+				Identifier: manifestCondition.Identifier,
+				Message:    condition.Message,
+			})
+			break
+		}
+		if len(failures) >= MaxManifestFailures {
+			break
+		}
+	}
+	return failures
+}
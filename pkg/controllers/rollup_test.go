@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+)
+
+func TestSummarizeWorks(t *testing.T) {
+	available := &multiclusterv1alpha1.Work{}
+	available.Status.Conditions = []multiclusterv1alpha1.StatusCondition{{Type: "Applied", Status: metav1.ConditionTrue}, {Type: "Available", Status: metav1.ConditionTrue}}
+	failed := &multiclusterv1alpha1.Work{}
+	failed.Status.Conditions = []multiclusterv1alpha1.StatusCondition{{Type: "Applied", Status: metav1.ConditionFalse, Reason: "WorkApplyFailed"}}
+	failed.Status.ManifestConditions = []multiclusterv1alpha1.ManifestCondition{
+		{
+			Identifier: multiclusterv1alpha1.ResourceIdentifier{Ordinal: 0, Kind: "ConfigMap", Name: "ok"},
+			Conditions: []multiclusterv1alpha1.StatusCondition{{Type: "Applied", Status: metav1.ConditionTrue}},
+		},
+		{
+			Identifier: multiclusterv1alpha1.ResourceIdentifier{Ordinal: 1, Kind: "ConfigMap", Name: "bad"},
+			Conditions: []multiclusterv1alpha1.StatusCondition{{Type: "Applied", Status: metav1.ConditionFalse, Message: "forbidden"}},
+		},
+	}
+
+	works := map[string]*multiclusterv1alpha1.Work{
+		"a": available,
+		"b": failed,
+		// The agent has not reported on c yet.
+		"c": {},
+	}
+	summary, failures := summarizeWorks([]string{"a", "b", "c", "d"}, works)
+
+	expected := multiclusterv1alpha1.WorkTemplateSummary{Clusters: 3, Applied: 1, Available: 1, Failed: 1}
+	if summary != expected {
+		t.Errorf("expected summary %+v, got %+v", expected, summary)
+	}
+	if len(failures) != 1 || failures[0].Cluster != "b" || failures[0].Reason != "WorkApplyFailed" {
+		t.Fatalf("unexpected failures %+v", failures)
+	}
+	if len(failures[0].Manifests) != 1 || failures[0].Manifests[0].Identifier.Name != "bad" || failures[0].Manifests[0].Message != "forbidden" {
+		t.Errorf("unexpected manifest failures %+v", failures[0].Manifests)
+	}
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// SpokeKubeconfigSecretKey is the key of the kubeconfig in a spoke secret.
+const SpokeKubeconfigSecretKey = "kubeconfig"
+
+// WorkNamespaceAnnotation sets the hub namespace of the works of a spoke secret.
+// The name of the secret is used if it is not set.
+const WorkNamespaceAnnotation = "multicluster.x-k8s.io/work-namespace"
+
+// StartPipelineFunc runs the work pipeline of a spoke cluster until stopCh is closed.
+type StartPipelineFunc func(cluster string, spokeConfig *rest.Config, workNamespace string, stopCh <-chan struct{}) error
+
+// SpokeSecretReconciler runs a work pipeline for every secret holding a spoke kubeconfig.
+// A pipeline is restarted when its secret changes, and stopped when its secret is deleted.
+// A pipeline which fails is restarted with an exponential backoff.
+type SpokeSecretReconciler struct {
+	client.Client
+	Log           logr.Logger
+	StartPipeline StartPipelineFunc
+
+	lock      sync.Mutex
+	pipelines map[ktypes.NamespacedName]*pipeline
+	backoff   workqueue.RateLimiter
+	// retries requeues the secrets of failed pipelines.
+	retries chan event.GenericEvent
+	// stopped is closed once the pipelines are stopped, so pending retries are dropped.
+	stopped     chan struct{}
+	stoppedOnce sync.Once
+}
+
+// pipeline tracks a running work pipeline.
+type pipeline struct {
+	stopCh          chan struct{}
+	resourceVersion string
+}
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+func (r *SpokeSecretReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("secret", req.NamespacedName)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.pipelines == nil {
+		r.pipelines = map[ktypes.NamespacedName]*pipeline{}
+		r.backoff = workqueue.NewItemExponentialFailureRateLimiter(time.Second, 5*time.Minute)
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, req.NamespacedName, secret)
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			r.stopPipeline(req.NamespacedName)
+			r.backoff.Forget(req.NamespacedName)
+			log.Info("stopped pipeline of deleted spoke secret")
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if running, found := r.pipelines[req.NamespacedName]; found && running.resourceVersion == secret.ResourceVersion {
+		return ctrl.Result{}, nil
+	}
+	r.stopPipeline(req.NamespacedName)
+
+	kubeconfig, found := secret.Data[SpokeKubeconfigSecretKey]
+	if !found {
+		// Other secrets, such as service account tokens, may share the namespace.
+		return ctrl.Result{}, nil
+	}
+	spokeConfig, err := spokeRESTConfig(kubeconfig)
+	if err != nil {
+		// The pipeline is started once the secret is fixed.
+		log.Error(err, "invalid spoke kubeconfig")
+		return ctrl.Result{}, nil
+	}
+	workNamespace := secret.Annotations[WorkNamespaceAnnotation]
+	if workNamespace == "" {
+		workNamespace = secret.Name
+	}
+
+	running := &pipeline{
+		stopCh:          make(chan struct{}),
+		resourceVersion: secret.ResourceVersion,
+	}
+	r.pipelines[req.NamespacedName] = running
+	go func() {
+		log.Info("starting pipeline", "workNamespace", workNamespace)
+		if err := r.StartPipeline(secret.Name, spokeConfig, workNamespace, running.stopCh); err != nil {
+			log.Error(err, "pipeline failed")
+			r.pipelineFailed(req.NamespacedName, running)
+		}
+	}()
+
+	return ctrl.Result{}, nil
+}
+
+// pipelineFailed forgets a failed pipeline, so it is started again when its secret is requeued after a backoff.
+func (r *SpokeSecretReconciler) pipelineFailed(name ktypes.NamespacedName, failed *pipeline) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.pipelines[name] != failed {
+		// The pipeline was stopped, or replaced since its secret changed.
+		return
+	}
+	delete(r.pipelines, name)
+
+	delay := r.backoff.When(name)
+	r.Log.Info("restarting failed pipeline", "secret", name, "after", delay)
+	retries, stopped := r.retries, r.stopped
+	time.AfterFunc(delay, func() {
+		select {
+		case retries <- event.GenericEvent{Meta: &metav1.ObjectMeta{Namespace: name.Namespace, Name: name.Name}}:
+		case <-stopped:
+		}
+	})
+}
+
+// StopPipelines stops every running pipeline, and drops the pending retries of failed pipelines.
+func (r *SpokeSecretReconciler) StopPipelines() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for name := range r.pipelines {
+		r.stopPipeline(name)
+	}
+	r.stoppedOnce.Do(func() {
+		if r.stopped != nil {
+			close(r.stopped)
+		}
+	})
+}
+
+// stopPipeline stops the pipeline of a secret if it is running.
+// The caller must hold the lock.
+func (r *SpokeSecretReconciler) stopPipeline(name ktypes.NamespacedName) {
+	if running, found := r.pipelines[name]; found {
+		close(running.stopCh)
+		delete(r.pipelines, name)
+	}
+}
+
+func (r *SpokeSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.retries = make(chan event.GenericEvent)
+	r.stopped = make(chan struct{})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Secret{}).
+		Watches(&source.Channel{Source: r.retries}, &handler.EnqueueRequestForObject{}).
+		Complete(r)
+}
+
+// spokeRESTConfig loads a spoke kubeconfig from a secret. Anyone able to write the secret must not be able to
+// run commands or read files in the agent, so exec and auth provider plugins and file references are rejected,
+// and credentials must be inline.
+func spokeRESTConfig(kubeconfig []byte) (*rest.Config, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	for name, cluster := range config.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return nil, fmt.Errorf("cluster %q references the file %s, use certificate-authority-data", name, cluster.CertificateAuthority)
+		}
+	}
+	for name, authInfo := range config.AuthInfos {
+		switch {
+		case authInfo.Exec != nil:
+			return nil, fmt.Errorf("user %q uses an exec plugin, which is not allowed in spoke kubeconfigs", name)
+		case authInfo.AuthProvider != nil:
+			return nil, fmt.Errorf("user %q uses an auth provider, which is not allowed in spoke kubeconfigs", name)
+		case authInfo.ClientCertificate != "":
+			return nil, fmt.Errorf("user %q references the file %s, use client-certificate-data", name, authInfo.ClientCertificate)
+		case authInfo.ClientKey != "":
+			return nil, fmt.Errorf("user %q references the file %s, use client-key-data", name, authInfo.ClientKey)
+		case authInfo.TokenFile != "":
+			return nil, fmt.Errorf("user %q references the file %s, use token", name, authInfo.TokenFile)
+		}
+	}
+	return clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+const spokeKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: spoke
+  cluster:
+    server: https://spoke.example.com
+contexts:
+- name: spoke
+  context:
+    cluster: spoke
+    user: spoke
+current-context: spoke
+users:
+- name: spoke
+  user:
+    token: token
+`
+
+// startedPipeline is a pipeline started by the fake StartPipelineFunc.
+type startedPipeline struct {
+	cluster       string
+	workNamespace string
+	stopCh        <-chan struct{}
+}
+
+// newFakeSpokeSecretReconciler returns a reconciler whose pipelines are sent to started, and which run
+// until they are stopped, or fail with the next error sent to failures.
+func newFakeSpokeSecretReconciler(secrets ...runtime.Object) (*SpokeSecretReconciler, chan startedPipeline, chan error) {
+	started := make(chan startedPipeline, 10)
+	failures := make(chan error)
+	r := &SpokeSecretReconciler{
+		Client: fake.NewFakeClient(secrets...),
+		Log:    ctrl.Log.WithName("controllers").WithName("SpokeSecret"),
+		StartPipeline: func(cluster string, spokeConfig *rest.Config, workNamespace string, stopCh <-chan struct{}) error {
+			started <- startedPipeline{cluster: cluster, workNamespace: workNamespace, stopCh: stopCh}
+			select {
+			case <-stopCh:
+				return nil
+			case err := <-failures:
+				return err
+			}
+		},
+		retries: make(chan event.GenericEvent, 10),
+		stopped: make(chan struct{}),
+	}
+	return r, started, failures
+}
+
+func spokeSecret(name string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "spokes", Name: name},
+		Data:       map[string][]byte{SpokeKubeconfigSecretKey: []byte(spokeKubeconfig)},
+	}
+}
+
+func reconcileSecret(t *testing.T, r *SpokeSecretReconciler, name string) {
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "spokes", Name: name}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func waitStarted(t *testing.T, started chan startedPipeline) startedPipeline {
+	select {
+	case running := <-started:
+		return running
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a pipeline to be started")
+	}
+	return startedPipeline{}
+}
+
+func waitStopped(t *testing.T, running startedPipeline) {
+	select {
+	case <-running.stopCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the pipeline to be stopped")
+	}
+}
+
+func TestSpokeSecretPipelines(t *testing.T) {
+	secret := spokeSecret("east")
+	secret.Annotations = map[string]string{WorkNamespaceAnnotation: "cluster-east"}
+	r, started, _ := newFakeSpokeSecretReconciler(secret)
+	defer r.StopPipelines()
+
+	// Added secrets start a pipeline, which is kept while the secret is unchanged.
+	reconcileSecret(t, r, "east")
+	first := waitStarted(t, started)
+	if first.cluster != "east" || first.workNamespace != "cluster-east" {
+		t.Errorf("unexpected pipeline %+v", first)
+	}
+	reconcileSecret(t, r, "east")
+	select {
+	case running := <-started:
+		t.Errorf("expected the pipeline to be kept, got %+v", running)
+	default:
+	}
+
+	// Updated secrets restart the pipeline.
+	secret.Data[SpokeKubeconfigSecretKey] = []byte(spokeKubeconfig + "\n")
+	if err := r.Update(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	reconcileSecret(t, r, "east")
+	waitStopped(t, first)
+	second := waitStarted(t, started)
+
+	// Deleted secrets stop the pipeline.
+	if err := r.Delete(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	reconcileSecret(t, r, "east")
+	waitStopped(t, second)
+}
+
+func TestSpokeSecretPipelineFailure(t *testing.T) {
+	r, started, failures := newFakeSpokeSecretReconciler(spokeSecret("west"))
+	r.pipelines = map[types.NamespacedName]*pipeline{}
+	r.backoff = workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond)
+	defer r.StopPipelines()
+
+	reconcileSecret(t, r, "west")
+	waitStarted(t, started)
+	failures <- errors.New("unable to reach the spoke cluster")
+
+	select {
+	case retry := <-r.retries:
+		if retry.Meta.GetNamespace() != "spokes" || retry.Meta.GetName() != "west" {
+			t.Errorf("unexpected retry of %s/%s", retry.Meta.GetNamespace(), retry.Meta.GetName())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the secret to be requeued")
+	}
+
+	// The failed pipeline is forgotten, so the unchanged secret starts it again.
+	reconcileSecret(t, r, "west")
+	waitStarted(t, started)
+}
+
+func TestSpokeRESTConfig(t *testing.T) {
+	cases := []struct {
+		name  string
+		user  string
+		valid bool
+	}{
+		{name: "inline token", user: "token: token", valid: true},
+		{name: "exec plugin", user: "exec:\n      apiVersion: client.authentication.k8s.io/v1beta1\n      command: /bin/sh"},
+		{name: "auth provider", user: "auth-provider:\n      name: gcp"},
+		{name: "client certificate file", user: "client-certificate: /etc/passwd"},
+		{name: "client key file", user: "client-key: /etc/passwd"},
+		{name: "token file", user: "tokenFile: /var/run/secrets/kubernetes.io/serviceaccount/token"},
+	}
+	for _, c := range cases {
+		kubeconfig := strings.Replace(spokeKubeconfig, "token: token", c.user, 1)
+		if _, err := spokeRESTConfig([]byte(kubeconfig)); (err == nil) != c.valid {
+			t.Errorf("%s: expected valid %t, got %v", c.name, c.valid, err)
+		}
+	}
+
+	caFile := strings.Replace(spokeKubeconfig, "server: https://spoke.example.com", "server: https://spoke.example.com\n    certificate-authority: /etc/ca.crt", 1)
+	if _, err := spokeRESTConfig([]byte(caFile)); err == nil {
+		t.Errorf("expected a certificate authority file to be rejected")
+	}
+}
+
+func TestSpokeSecretRejectedKubeconfig(t *testing.T) {
+	secret := spokeSecret("east")
+	secret.Data[SpokeKubeconfigSecretKey] = []byte(strings.Replace(spokeKubeconfig, "token: token", "tokenFile: /etc/passwd", 1))
+	r, started, _ := newFakeSpokeSecretReconciler(secret)
+	defer r.StopPipelines()
+
+	reconcileSecret(t, r, "east")
+	select {
+	case running := <-started:
+		t.Errorf("expected no pipeline to be started, got %+v", running)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
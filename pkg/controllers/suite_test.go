@@ -36,6 +36,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/inventory"
+	"github.com/vllry/cluster-reconciler/pkg/kustomize"
 	"github.com/vllry/cluster-reconciler/pkg/restmapper"
 	// +kubebuilder:scaffold:imports
 )
@@ -104,6 +106,9 @@ var _ = BeforeSuite(func(done Done) {
 		SpokeKubeClient:    k8sClient,
 		SpokeDynamicClient: dynamicClient,
 		RestMapper:         restMapper,
+		Kustomize:          kustomize.NewRenderer(""),
+		Git:                inventory.NewGitRepositories(""),
+		Recorder:           workManager.GetEventRecorderFor("work-controller"),
 	}).SetupWithManager(workManager)
 	Expect(err).ToNot(HaveOccurred())
 
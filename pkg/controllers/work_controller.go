@@ -18,22 +18,30 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/encryption"
 	"github.com/vllry/cluster-reconciler/pkg/helpers"
+	"github.com/vllry/cluster-reconciler/pkg/inventory"
+	"github.com/vllry/cluster-reconciler/pkg/kustomize"
+	"github.com/vllry/cluster-reconciler/pkg/parse"
 	"github.com/vllry/cluster-reconciler/pkg/reconcile"
 	"github.com/vllry/cluster-reconciler/pkg/restmapper"
+	"github.com/vllry/cluster-reconciler/pkg/signature"
 	"github.com/vllry/cluster-reconciler/pkg/types"
 )
 
@@ -45,12 +53,25 @@ type WorkReconciler struct {
 	SpokeKubeClient    kubernetes.Interface
 	SpokeDynamicClient dynamic.Interface
 	RestMapper         *restmapper.Mapper
+	Kustomize          *kustomize.Renderer
+	Git                *inventory.GitRepositories
+	KeySource          *encryption.KeySource
+	TrustedKeys        *signature.TrustedKeySource
+	Recorder           record.EventRecorder
+	// ReportOnly compares every work with the spoke cluster without writing to it.
+	ReportOnly bool
+	// ClusterName names the spoke cluster, when the process manages more than one.
+	ClusterName string
 }
 
 const workFinalizer = "work-clean-up"
 
+// degradedAfter is how long a work fails to be applied before it is degraded.
+var degradedAfter = 5 * time.Minute
+
 // +kubebuilder:rbac:groups=multicluster.x-k8s.io,resources=works,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=multicluster.x-k8s.io,resources=works/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *WorkReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
@@ -80,56 +101,226 @@ func (r *WorkReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	// Spoke cluster is deleting, we remove its related resources
 	if !work.DeletionTimestamp.IsZero() {
-		if err := r.removeWorkResources(ctx, work); err != nil {
+		if work.Spec.Suspend {
+			// Nothing is written to the spoke cluster for a suspended work, so its resources are left in place.
+			log.Info("work is suspended, its resources are left on the spoke cluster")
+		} else if err := r.removeWorkResources(ctx, work); err != nil {
 			return ctrl.Result{}, err
 		}
+		forgetDrift(work)
 		return ctrl.Result{}, r.removeWorkFinalizer(ctx, work)
 	}
 
-	results, _ := reconcile.ReconcileCluster(r.SpokeKubeClient, r.SpokeDynamicClient, r.fetchFromWork(work))
-	desiredManifestConditionsMap := generateAppliedConditionFromResults(results)
+	work.Status.ObservedGeneration = work.Generation
+	if work.Spec.Suspend || work.Spec.ReportOnly || r.ReportOnly {
+		return pollGitWork(work, ctrl.Result{}), r.reportObservedWork(ctx, work)
+	}
+	forgetDrift(work)
+
+	var signatureCondition *multiclusterv1alpha1.StatusCondition
+	if r.TrustedKeys != nil {
+		signatureCondition = r.verifySignatures(work)
+		if helpers.IsConditionTrue(signatureCondition) {
+			log.Info("refusing to apply work with invalid signature", "reason", signatureCondition.Reason)
+			// The work is neither suspended nor report only, conditions of those modes are stale.
+			helpers.RemoveWorkCondition(&work.Status.Conditions, "InSync")
+			helpers.RemoveWorkCondition(&work.Status.Conditions, "Suspended")
+			helpers.SetWorkCondition(&work.Status.Conditions, *signatureCondition)
+			helpers.SetWorkCondition(&work.Status.Conditions, multiclusterv1alpha1.StatusCondition{
+				Type:    "Applied",
+				Status:  metav1.ConditionFalse,
+				Reason:  "WorkApplyRefused",
+				Message: "Manifests in work are not applied since the signature is invalid",
+			})
+			return ctrl.Result{}, r.Status().Update(ctx, work)
+		}
+	}
+
+	source := r.workSource(work)
+	results, err := reconcile.ReconcileCluster(r.SpokeKubeClient, r.SpokeDynamicClient, inventory.FetchFunc(source, inventory.Resolver(r.RestMapper)), reconcile.ReconcileOptions{DryRun: work.Spec.DryRun})
+	r.recordSyncedCommit(work, source)
+	if err != nil {
+		log.Error(err, "unable to reconcile work")
+	}
+	r.recordDiffEvents(work, results)
+
+	var desiredManifestConditionsMap map[multiclusterv1alpha1.ResourceIdentifier]multiclusterv1alpha1.ManifestCondition
+	if work.Spec.DryRun {
+		desiredManifestConditionsMap = generateDryRunConditionFromResults(results)
+	} else {
+		desiredManifestConditionsMap = generateAppliedConditionFromResults(results)
+	}
 	currentManifestConditions := work.Status.ManifestConditions
 	if currentManifestConditions == nil {
 		currentManifestConditions = []multiclusterv1alpha1.ManifestCondition{}
 	}
 	desiredManifestConditions := mergeManifestConditions(desiredManifestConditionsMap, currentManifestConditions)
 	work.Status.ManifestConditions = desiredManifestConditions
-	work.Status.Conditions = generateWorkConditionsFromManifestConditions(desiredManifestConditions)
+	var workConditions []multiclusterv1alpha1.StatusCondition
+	result := ctrl.Result{}
+	if work.Spec.DryRun {
+		workConditions = generateWorkDryRunConditionsFromManifestConditions(desiredManifestConditions)
+	} else {
+		workConditions = generateWorkConditionsFromManifestConditions(desiredManifestConditions)
+		previousApplied := helpers.FindWorkCondition(work.Status.Conditions, "Applied")
+		var degraded multiclusterv1alpha1.StatusCondition
+		degraded, result.RequeueAfter = generateWorkDegradedCondition(previousApplied, workConditions[0], time.Now())
+		workConditions = append(workConditions, degraded)
+	}
+	if signatureCondition != nil {
+		workConditions = append(workConditions, *signatureCondition)
+	}
+	work.Status.Conditions = MergeStatusConditions(work.Status.Conditions, workConditions)
 
 	err = r.Status().Update(ctx, work)
 
-	return ctrl.Result{}, err
+	return pollGitWork(work, result), err
 }
 
-func (r *WorkReconciler) fetchFromWork(work *multiclusterv1alpha1.Work) reconcile.FetchDesiredObjectFunc {
-	return func() (map[types.ResourceIdentifier]types.Semistructured, error) {
-		desired := map[types.ResourceIdentifier]types.Semistructured{}
-		for index, manifest := range work.Spec.Workload.Manifests {
-			unstrcturedObj := &unstructured.Unstructured{}
-			err := unstrcturedObj.UnmarshalJSON(manifest.Raw)
-			if err != nil {
-				identifier := types.ResourceIdentifier{Ordinal: index}
-				desired[identifier] = types.Semistructured{Identifier: identifier}
-				continue
-			}
+// pollGitWork requeues works read from git after inventory.PollInterval, since changes to the
+// repository do not trigger a reconcile.
+func pollGitWork(work *multiclusterv1alpha1.Work, result ctrl.Result) ctrl.Result {
+	if work.Spec.Workload.Git == nil {
+		return result
+	}
+	if result.RequeueAfter == 0 || inventory.PollInterval < result.RequeueAfter {
+		result.RequeueAfter = inventory.PollInterval
+	}
+	return result
+}
 
-			semi, err := types.UnstructuredToSemistructured(*unstrcturedObj)
-			if err != nil {
-				semi.Identifier.Ordinal = index
-				desired[semi.Identifier] = semi
-				continue
-			}
-			mapping, err := r.RestMapper.MappingForGVK(semi.Identifier.GroupVersionKind)
-			if err != nil {
-				semi.Identifier.Ordinal = index
-				desired[semi.Identifier] = semi
-				continue
-			}
-			semi.Identifier.GroupVersionResource = mapping.Resource
-			desired[semi.Identifier] = semi
+// workSource returns the inventory source reading the workload of work.
+func (r *WorkReconciler) workSource(work *multiclusterv1alpha1.Work) *inventory.WorkSource {
+	return &inventory.WorkSource{
+		Work:      work,
+		Kustomize: r.Kustomize,
+		KeySource: r.KeySource,
+		Git:       r.Git,
+		Mapper:    r.RestMapper,
+	}
+}
+
+// recordSyncedCommit records the commit of the git source which was last read.
+func (r *WorkReconciler) recordSyncedCommit(work *multiclusterv1alpha1.Work, source *inventory.WorkSource) {
+	commit := source.LastSyncedCommit()
+	if commit == "" || commit == work.Status.LastSyncedCommit {
+		return
+	}
+	gitSource := work.Spec.Workload.Git
+	r.Log.Info("synced git commit", "work", work.Namespace+"/"+work.Name, "url", gitSource.URL, "ref", gitSource.Ref, "commit", commit)
+	work.Status.LastSyncedCommit = commit
+}
+
+// reportObservedWork compares the workload with the spoke cluster without writing to it,
+// and reports whether each manifest is in sync alongside the conditions of the last apply.
+func (r *WorkReconciler) reportObservedWork(ctx context.Context, work *multiclusterv1alpha1.Work) error {
+	inSyncCondition := multiclusterv1alpha1.StatusCondition{
+		Type:    "InSync",
+		Status:  metav1.ConditionTrue,
+		Reason:  "WorkInSync",
+		Message: "Resources match the manifests in work",
+	}
+	if work.Spec.Suspend {
+		helpers.SetWorkCondition(&work.Status.Conditions, multiclusterv1alpha1.StatusCondition{
+			Type:    "Suspended",
+			Status:  metav1.ConditionTrue,
+			Reason:  "WorkSuspended",
+			Message: "Manifests in work are not written to the spoke cluster",
+		})
+	} else {
+		// The work was resumed but is still report only.
+		helpers.RemoveWorkCondition(&work.Status.Conditions, "Suspended")
+	}
+
+	source := r.workSource(work)
+	results, err := reconcile.ReconcileCluster(r.SpokeKubeClient, r.SpokeDynamicClient, inventory.FetchFunc(source, inventory.Resolver(r.RestMapper)), reconcile.ReconcileOptions{ReadOnly: true})
+	r.recordSyncedCommit(work, source)
+	if err != nil {
+		inSyncCondition.Status = metav1.ConditionUnknown
+		inSyncCondition.Reason = "WorkCheckFailed"
+		inSyncCondition.Message = fmt.Sprintf("Failed to compare the manifests with err: %v", err)
+		helpers.SetWorkCondition(&work.Status.Conditions, inSyncCondition)
+		if statusErr := r.Status().Update(ctx, work); statusErr != nil {
+			return statusErr
+		}
+		return err
+	}
+
+	work.Status.ManifestConditions = mergeObservedManifestConditions(generateInSyncConditionFromResults(results), work.Status.ManifestConditions)
+	drifted, inSync := 0, 0
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			// Manifests which failed to parse or to be fetched are neither drifted nor in sync.
+		case result.Drifted:
+			drifted++
+		default:
+			inSync++
 		}
-		return desired, nil
 	}
+	recordDrift(work, drifted, inSync)
+	if drifted > 0 {
+		inSyncCondition.Status = metav1.ConditionFalse
+		inSyncCondition.Reason = "WorkDrifted"
+		inSyncCondition.Message = fmt.Sprintf("%d of %d resources do not match the manifests in work", drifted, len(results))
+	} else if failed := len(results) - inSync; failed > 0 {
+		inSyncCondition.Status = metav1.ConditionUnknown
+		inSyncCondition.Reason = "WorkCheckFailed"
+		inSyncCondition.Message = fmt.Sprintf("%d of %d resources could not be compared with the manifests in work", failed, len(results))
+	}
+	helpers.SetWorkCondition(&work.Status.Conditions, inSyncCondition)
+	helpers.SetWorkCondition(&work.Status.Conditions, generateWorkAvailableCondition(work.Status.ManifestConditions))
+
+	return r.Status().Update(ctx, work)
+}
+
+// recordDiffEvents records an event on the work for every updated resource, with the fields which changed.
+func (r *WorkReconciler) recordDiffEvents(work *multiclusterv1alpha1.Work, results []reconcile.ReconcileResult) {
+	if r.Recorder == nil {
+		return
+	}
+	reason := "ManifestUpdated"
+	if work.Spec.DryRun {
+		reason = "ManifestWouldUpdate"
+	}
+	for _, result := range results {
+		if !result.Updated || result.Err != nil || len(result.Diff) == 0 {
+			continue
+		}
+		r.Recorder.Eventf(work, corev1.EventTypeNormal, reason, "%s %s changed: %s",
+			result.Identifier.GroupVersionKind.Kind, result.Identifier.NamespacedName, strings.Join(result.Diff, "; "))
+	}
+}
+
+// verifySignatures returns the SignatureInvalid condition of the work, which is true if the work is not signed
+// by a trusted key.
+func (r *WorkReconciler) verifySignatures(work *multiclusterv1alpha1.Work) *multiclusterv1alpha1.StatusCondition {
+	condition := &multiclusterv1alpha1.StatusCondition{
+		Type:    "SignatureInvalid",
+		Status:  metav1.ConditionFalse,
+		Reason:  "SignatureVerified",
+		Message: "The workload is signed by a trusted key",
+	}
+
+	trustedKeys, err := r.TrustedKeys.Keys()
+	if err != nil {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "TrustedKeysUnavailable"
+		condition.Message = fmt.Sprintf("Failed to load the trusted keys with err: %v", err)
+		return condition
+	}
+
+	err = signature.Verify(trustedKeys, work.Spec.Workload, work.Spec.Defaults, work.Spec.Signatures)
+	if err == signature.ErrUnsigned {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "SignatureMissing"
+		condition.Message = "The workload is not signed"
+	} else if err != nil {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "SignatureMismatch"
+		condition.Message = fmt.Sprintf("Failed to verify the workload with err: %v", err)
+	}
+	return condition
 }
 
 func (r *WorkReconciler) removeWorkResources(ctx context.Context, work *multiclusterv1alpha1.Work) error {
@@ -156,9 +347,12 @@ func (r *WorkReconciler) removeWorkFinalizer(ctx context.Context, work *multiclu
 }
 
 func (r *WorkReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&multiclusterv1alpha1.Work{}).
-		Complete(r)
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&multiclusterv1alpha1.Work{})
+	if r.ClusterName != "" {
+		builder = builder.Named("work-" + r.ClusterName)
+	}
+	return builder.Complete(r)
 }
 
 // mergeManifestConditions merges the desired cond from current cond
@@ -183,10 +377,42 @@ func mergeManifestConditions(desired map[multiclusterv1alpha1.ResourceIdentifier
 	return resultConds
 }
 
+// mergeObservedManifestConditions merges the desired cond into current cond like mergeManifestConditions,
+// but keeps the existing condition types which are not in the desired cond.
+func mergeObservedManifestConditions(desired map[multiclusterv1alpha1.ResourceIdentifier]multiclusterv1alpha1.ManifestCondition, current []multiclusterv1alpha1.ManifestCondition) []multiclusterv1alpha1.ManifestCondition {
+	resultConds := []multiclusterv1alpha1.ManifestCondition{}
+	currentCondMap := map[multiclusterv1alpha1.ResourceIdentifier]multiclusterv1alpha1.ManifestCondition{}
+
+	for _, cond := range current {
+		currentCondMap[cond.Identifier] = cond
+	}
+
+	for _, desiredCond := range desired {
+		curCond, found := currentCondMap[desiredCond.Identifier]
+		if !found {
+			resultConds = append(resultConds, desiredCond)
+			continue
+		}
+		merged := curCond.DeepCopy()
+		for _, cond := range desiredCond.Conditions {
+			helpers.SetWorkCondition(&merged.Conditions, cond)
+		}
+		merged.Diff = desiredCond.Diff
+		resultConds = append(resultConds, *merged)
+	}
+	return resultConds
+}
+
 func mergeManifestCondition(condition, newCondition multiclusterv1alpha1.ManifestCondition) multiclusterv1alpha1.ManifestCondition {
+	diff := newCondition.Diff
+	if len(diff) == 0 {
+		// Keep the diff of the last update.
+		diff = condition.Diff
+	}
 	return multiclusterv1alpha1.ManifestCondition{
 		Identifier: newCondition.Identifier,
 		Conditions: MergeStatusConditions(condition.Conditions, newCondition.Conditions),
+		Diff:       diff,
 	}
 }
 
@@ -256,23 +482,158 @@ func generateWorkConditionsFromManifestConditions(manifestConditions []multiclus
 		}
 	}
 
-	return []multiclusterv1alpha1.StatusCondition{workAppliedCondition}
+	return []multiclusterv1alpha1.StatusCondition{workAppliedCondition, generateWorkAvailableCondition(manifestConditions)}
+}
+
+// generateWorkAvailableCondition returns the Available condition of a work, which is true if the resource of
+// every manifest exists on the spoke cluster.
+func generateWorkAvailableCondition(manifestConditions []multiclusterv1alpha1.ManifestCondition) multiclusterv1alpha1.StatusCondition {
+	workAvailableCondition := multiclusterv1alpha1.StatusCondition{
+		Type:               "Available",
+		Status:             metav1.ConditionTrue,
+		Reason:             "WorkAvailable",
+		Message:            "Resources of the manifests in work exist on the spoke cluster",
+		LastTransitionTime: metav1.Now(),
+	}
+
+	for _, manifestCond := range manifestConditions {
+		if cond := helpers.FindWorkCondition(manifestCond.Conditions, "Available"); !helpers.IsConditionTrue(cond) {
+			workAvailableCondition.Status = metav1.ConditionFalse
+			workAvailableCondition.Reason = "WorkNotAvailable"
+			workAvailableCondition.Message = fmt.Sprintf("Resource with identifier %#v does not exist on the spoke cluster", manifestCond.Identifier)
+		}
+	}
+	return workAvailableCondition
+}
+
+// generateWorkDegradedCondition returns the Degraded condition of a work, which is true once the work has failed
+// to be applied for degradedAfter. previous is the Applied condition before the work was applied, and the
+// returned duration is when the condition must be checked again, if the work may still become degraded.
+func generateWorkDegradedCondition(previous *multiclusterv1alpha1.StatusCondition, applied multiclusterv1alpha1.StatusCondition, now time.Time) (multiclusterv1alpha1.StatusCondition, time.Duration) {
+	workDegradedCondition := multiclusterv1alpha1.StatusCondition{
+		Type:               "Degraded",
+		Status:             metav1.ConditionFalse,
+		Reason:             "WorkNotDegraded",
+		Message:            "Manifests in work are applied",
+		LastTransitionTime: metav1.Now(),
+	}
+	if applied.Status != metav1.ConditionFalse {
+		return workDegradedCondition, 0
+	}
+
+	failingSince := now
+	if previous != nil && previous.Status == metav1.ConditionFalse && !previous.LastTransitionTime.IsZero() {
+		failingSince = previous.LastTransitionTime.Time
+	}
+	if failing := now.Sub(failingSince); failing < degradedAfter {
+		workDegradedCondition.Message = "Manifests in work failed to be applied recently"
+		return workDegradedCondition, degradedAfter - failing
+	}
+	workDegradedCondition.Status = metav1.ConditionTrue
+	workDegradedCondition.Reason = "WorkDegraded"
+	workDegradedCondition.Message = fmt.Sprintf("Manifests in work have failed to be applied since %s: %s", failingSince.Format(time.RFC3339), applied.Message)
+	return workDegradedCondition, 0
+}
+
+func generateWorkDryRunConditionsFromManifestConditions(manifestConditions []multiclusterv1alpha1.ManifestCondition) []multiclusterv1alpha1.StatusCondition {
+	// If all manifests would be applied, set work dry run condition as true
+	workDryRunCondition := multiclusterv1alpha1.StatusCondition{
+		Type:               "DryRun",
+		Status:             metav1.ConditionTrue,
+		Reason:             "WorkDryRunDone",
+		Message:            "Manifests in work would be applied",
+		LastTransitionTime: metav1.Now(),
+	}
+
+	for _, manifestCond := range manifestConditions {
+		cond := helpers.FindWorkCondition(manifestCond.Conditions, "DryRun")
+		if cond == nil || cond.Status == metav1.ConditionFalse {
+			workDryRunCondition.Status = metav1.ConditionFalse
+			workDryRunCondition.Reason = "WorkDryRunFailed"
+			workDryRunCondition.Message = fmt.Sprintf("Resource with identifier %#v would fail to be applied", manifestCond.Identifier)
+			if cond != nil {
+				workDryRunCondition.Message = fmt.Sprintf("%s with err %v", workDryRunCondition.Message, cond.Message)
+			}
+		}
+	}
+
+	return []multiclusterv1alpha1.StatusCondition{workDryRunCondition}
+}
+
+func generateDryRunConditionFromResults(results []reconcile.ReconcileResult) map[multiclusterv1alpha1.ResourceIdentifier]multiclusterv1alpha1.ManifestCondition {
+	conditions := map[multiclusterv1alpha1.ResourceIdentifier]multiclusterv1alpha1.ManifestCondition{}
+	for _, result := range results {
+		condition := multiclusterv1alpha1.ManifestCondition{
+			Identifier: toManifestIdentifier(result.Identifier),
+			Conditions: []multiclusterv1alpha1.StatusCondition{},
+			Diff:       result.Diff,
+		}
+
+		cond := multiclusterv1alpha1.StatusCondition{
+			Type:               "DryRun",
+			Status:             metav1.ConditionTrue,
+			Reason:             "ManifestDryRunDone",
+			Message:            "The manifest would be applied successfully",
+			LastTransitionTime: metav1.Now(),
+		}
+
+		if isParseError(result.Err) {
+			cond.Status = metav1.ConditionFalse
+			cond.Reason = "ManifestParseFailed"
+			cond.Message = fmt.Sprintf("Failed to parse the manifest with err: %v", result.Err)
+		} else if result.Err != nil {
+			cond.Status = metav1.ConditionFalse
+			cond.Reason = "ManifestDryRunFailed"
+			cond.Message = fmt.Sprintf("The manifest would fail to be applied with err: %v", result.Err)
+		}
+		helpers.SetWorkCondition(&condition.Conditions, cond)
+		conditions[condition.Identifier] = condition
+	}
+
+	return conditions
+}
+
+func generateInSyncConditionFromResults(results []reconcile.ReconcileResult) map[multiclusterv1alpha1.ResourceIdentifier]multiclusterv1alpha1.ManifestCondition {
+	conditions := map[multiclusterv1alpha1.ResourceIdentifier]multiclusterv1alpha1.ManifestCondition{}
+	for _, result := range results {
+		condition := multiclusterv1alpha1.ManifestCondition{
+			Identifier: toManifestIdentifier(result.Identifier),
+			Conditions: []multiclusterv1alpha1.StatusCondition{},
+			Diff:       result.Diff,
+		}
+
+		cond := multiclusterv1alpha1.StatusCondition{
+			Type:               "InSync",
+			Status:             metav1.ConditionTrue,
+			Reason:             "ManifestInSync",
+			Message:            "The resource matches the manifest",
+			LastTransitionTime: metav1.Now(),
+		}
+
+		if result.Err != nil {
+			cond.Status = metav1.ConditionUnknown
+			cond.Reason = "ManifestCheckFailed"
+			cond.Message = fmt.Sprintf("Failed to compare the manifest with err: %v", result.Err)
+		} else if result.Drifted {
+			cond.Status = metav1.ConditionFalse
+			cond.Reason = "ManifestDrifted"
+			cond.Message = "The resource does not match the manifest"
+		}
+		helpers.SetWorkCondition(&condition.Conditions, cond)
+		helpers.SetWorkCondition(&condition.Conditions, availableCondition(result))
+		conditions[condition.Identifier] = condition
+	}
+
+	return conditions
 }
 
 func generateAppliedConditionFromResults(results []reconcile.ReconcileResult) map[multiclusterv1alpha1.ResourceIdentifier]multiclusterv1alpha1.ManifestCondition {
 	conditions := map[multiclusterv1alpha1.ResourceIdentifier]multiclusterv1alpha1.ManifestCondition{}
 	for _, result := range results {
 		condition := multiclusterv1alpha1.ManifestCondition{
-			Identifier: multiclusterv1alpha1.ResourceIdentifier{
-				Ordinal:   result.Identifier.Ordinal,
-				Group:     result.Identifier.GroupVersionKind.Group,
-				Version:   result.Identifier.GroupVersionKind.Version,
-				Kind:      result.Identifier.GroupVersionKind.Kind,
-				Resource:  result.Identifier.GroupVersionResource.Resource,
-				Namespace: result.Identifier.NamespacedName.Namespace,
-				Name:      result.Identifier.NamespacedName.Name,
-			},
+			Identifier: toManifestIdentifier(result.Identifier),
 			Conditions: []multiclusterv1alpha1.StatusCondition{},
+			Diff:       result.Diff,
 		}
 
 		cond := multiclusterv1alpha1.StatusCondition{
@@ -283,14 +644,61 @@ func generateAppliedConditionFromResults(results []reconcile.ReconcileResult) ma
 			LastTransitionTime: metav1.Now(),
 		}
 
-		if result.Err != nil {
+		if isParseError(result.Err) {
+			cond.Status = metav1.ConditionFalse
+			cond.Reason = "ManifestParseFailed"
+			cond.Message = fmt.Sprintf("Failed to parse the manifest with err: %v", result.Err)
+		} else if result.Err != nil {
 			cond.Status = metav1.ConditionFalse
 			cond.Reason = "ManifestApplyFailed"
 			cond.Message = fmt.Sprintf("Failed to apply the manifest with err: %v", result.Err)
 		}
 		helpers.SetWorkCondition(&condition.Conditions, cond)
+		helpers.SetWorkCondition(&condition.Conditions, availableCondition(result))
 		conditions[condition.Identifier] = condition
 	}
 
 	return conditions
 }
+
+// availableCondition returns the Available condition of a manifest, which is true if its resource exists on
+// the spoke cluster.
+func availableCondition(result reconcile.ReconcileResult) multiclusterv1alpha1.StatusCondition {
+	if !result.Exists {
+		return multiclusterv1alpha1.StatusCondition{
+			Type:               "Available",
+			Status:             metav1.ConditionFalse,
+			Reason:             "ManifestNotAvailable",
+			Message:            "The resource does not exist on the spoke cluster",
+			LastTransitionTime: metav1.Now(),
+		}
+	}
+	return multiclusterv1alpha1.StatusCondition{
+		Type:               "Available",
+		Status:             metav1.ConditionTrue,
+		Reason:             "ManifestAvailable",
+		Message:            "The resource exists on the spoke cluster",
+		LastTransitionTime: metav1.Now(),
+	}
+}
+
+// isParseError returns true if err is an error parsing a manifest, which carries where it was found.
+func isParseError(err error) bool {
+	var parseErr *parse.Error
+	return errors.As(err, &parseErr)
+}
+
+// toManifestIdentifier converts a resource identifier to the identifier reported in the work status.
+func toManifestIdentifier(identifier types.ResourceIdentifier) multiclusterv1alpha1.ResourceIdentifier {
+	return multiclusterv1alpha1.ResourceIdentifier{
+		Ordinal:   identifier.Ordinal,
+		Source:    identifier.Source,
+		Item:      identifier.Item,
+		Group:     identifier.GroupVersionKind.Group,
+		Version:   identifier.GroupVersionKind.Version,
+		Kind:      identifier.GroupVersionKind.Kind,
+		Resource:  identifier.GroupVersionResource.Resource,
+		Namespace: identifier.NamespacedName.Namespace,
+		Name:      identifier.NamespacedName.Name,
+	}
+}
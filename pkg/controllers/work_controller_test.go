@@ -3,17 +3,29 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"testing"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
 	"github.com/vllry/cluster-reconciler/pkg/helpers"
+	"github.com/vllry/cluster-reconciler/pkg/inventory"
+	"github.com/vllry/cluster-reconciler/pkg/restmapper"
+	"github.com/vllry/cluster-reconciler/pkg/signature"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -112,3 +124,250 @@ var _ = Describe("Work Controller", func() {
 		})
 	})
 })
+
+// newFakeWorkReconciler returns a reconciler of the works in the hub, applying them to an empty spoke cluster
+// which serves ConfigMaps.
+func newFakeWorkReconciler(t *testing.T, works ...runtime.Object) *WorkReconciler {
+	scheme := runtime.NewScheme()
+	if err := multiclusterv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	return &WorkReconciler{
+		Client:             fake.NewFakeClientWithScheme(scheme, works...),
+		Log:                ctrl.Log.WithName("controllers").WithName("Work"),
+		Scheme:             scheme,
+		SpokeKubeClient:    kubefake.NewSimpleClientset(),
+		SpokeDynamicClient: fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()),
+		RestMapper:         &restmapper.Mapper{Mapper: mapper},
+	}
+}
+
+// fakeWork returns a work in the cluster namespace holding a ConfigMap manifest.
+func fakeWork(name string) *multiclusterv1alpha1.Work {
+	return &multiclusterv1alpha1.Work{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "cluster",
+			Name:       name,
+			Finalizers: []string{workFinalizer},
+		},
+		Spec: multiclusterv1alpha1.WorkSpec{
+			Workload: multiclusterv1alpha1.WorkloadTemplate{
+				Manifests: []multiclusterv1alpha1.Manifest{
+					{RawExtension: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"default"}}`)}},
+				},
+			},
+		},
+	}
+}
+
+// reconcileWork reconciles the work, and returns it as updated by the reconciler.
+func reconcileWork(t *testing.T, r *WorkReconciler, work *multiclusterv1alpha1.Work) *multiclusterv1alpha1.Work {
+	key := types.NamespacedName{Namespace: work.Namespace, Name: work.Name}
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated := &multiclusterv1alpha1.Work{}
+	if err := r.Get(context.Background(), key, updated); err != nil {
+		t.Fatal(err)
+	}
+	return updated
+}
+
+func TestReconcileRefusedSignature(t *testing.T) {
+	work := fakeWork("unsigned")
+	work.Status.Conditions = []multiclusterv1alpha1.StatusCondition{
+		{Type: "InSync", Status: metav1.ConditionTrue},
+		{Type: "Suspended", Status: metav1.ConditionTrue},
+	}
+	r := newFakeWorkReconciler(t, work)
+	r.SpokeKubeClient = kubefake.NewSimpleClientset(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "spoke", Name: "trusted-keys"}})
+	r.TrustedKeys = signature.NewTrustedKeySource(r.SpokeKubeClient, "spoke", "trusted-keys")
+
+	updated := reconcileWork(t, r, work)
+	for _, conditionType := range []string{"InSync", "Suspended"} {
+		if helpers.FindWorkCondition(updated.Status.Conditions, conditionType) != nil {
+			t.Errorf("expected the stale %s condition to be removed", conditionType)
+		}
+	}
+	if cond := helpers.FindWorkCondition(updated.Status.Conditions, "Applied"); cond == nil || cond.Reason != "WorkApplyRefused" {
+		t.Errorf("expected the apply to be refused, got %+v", cond)
+	}
+	if cond := helpers.FindWorkCondition(updated.Status.Conditions, "SignatureInvalid"); cond == nil || cond.Reason != "SignatureMissing" {
+		t.Errorf("expected the signature to be missing, got %+v", cond)
+	}
+}
+
+func TestReconcileDryRun(t *testing.T) {
+	work := fakeWork("dry-run")
+	work.Spec.DryRun = true
+	work.Spec.Workload.Manifests = append(work.Spec.Workload.Manifests,
+		multiclusterv1alpha1.Manifest{RawExtension: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap"}`)}})
+	r := newFakeWorkReconciler(t, work)
+
+	updated := reconcileWork(t, r, work)
+	if cond := helpers.FindWorkCondition(updated.Status.Conditions, "DryRun"); cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "WorkDryRunFailed" {
+		t.Errorf("expected the dry run of the work to fail, got %+v", cond)
+	}
+	if helpers.FindWorkCondition(updated.Status.Conditions, "Applied") != nil {
+		t.Errorf("expected no Applied condition on a dry run")
+	}
+	if len(updated.Status.ManifestConditions) != 2 {
+		t.Fatalf("expected a condition per manifest, got %+v", updated.Status.ManifestConditions)
+	}
+	reasons := map[string]string{}
+	for _, manifestCondition := range updated.Status.ManifestConditions {
+		cond := helpers.FindWorkCondition(manifestCondition.Conditions, "DryRun")
+		if cond == nil {
+			t.Fatalf("expected a DryRun condition on %+v", manifestCondition.Identifier)
+		}
+		reasons[manifestCondition.Identifier.Name] = cond.Reason
+	}
+	if reasons["cm"] != "ManifestDryRunDone" || reasons[""] != "ManifestDryRunFailed" {
+		t.Errorf("unexpected manifest conditions %v", reasons)
+	}
+}
+
+func TestReconcileSuspended(t *testing.T) {
+	work := fakeWork("suspended")
+	work.Spec.Suspend = true
+	r := newFakeWorkReconciler(t, work)
+
+	updated := reconcileWork(t, r, work)
+	if cond := helpers.FindWorkCondition(updated.Status.Conditions, "Suspended"); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Errorf("expected the work to be suspended, got %+v", cond)
+	}
+	if cond := helpers.FindWorkCondition(updated.Status.Conditions, "InSync"); cond == nil || cond.Reason != "WorkDrifted" {
+		t.Errorf("expected the missing resource to be reported, got %+v", cond)
+	}
+	list, err := r.SpokeDynamicClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 0 {
+		t.Errorf("expected nothing to be written to the spoke cluster, got %v", list.Items)
+	}
+}
+
+func TestReconcileDeletingSuspended(t *testing.T) {
+	work := fakeWork("deleting")
+	work.Spec.Suspend = true
+	now := metav1.Now()
+	work.DeletionTimestamp = &now
+	r := newFakeWorkReconciler(t, work)
+
+	updated := reconcileWork(t, r, work)
+	if len(updated.Finalizers) != 0 {
+		t.Errorf("expected the finalizer to be removed, got %v", updated.Finalizers)
+	}
+}
+
+func TestReconcileReportOnly(t *testing.T) {
+	work := fakeWork("report-only")
+	work.Spec.ReportOnly = true
+	work.Spec.Workload.Manifests = append(work.Spec.Workload.Manifests,
+		multiclusterv1alpha1.Manifest{RawExtension: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap"}`)}})
+	// The work was suspended before it was resumed as report only.
+	work.Status.Conditions = []multiclusterv1alpha1.StatusCondition{{Type: "Suspended", Status: metav1.ConditionTrue}}
+	r := newFakeWorkReconciler(t, work)
+	live := &unstructured.Unstructured{}
+	if err := live.UnmarshalJSON(work.Spec.Workload.Manifests[0].Raw); err != nil {
+		t.Fatal(err)
+	}
+	r.SpokeDynamicClient = fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), live)
+
+	updated := reconcileWork(t, r, work)
+	if helpers.FindWorkCondition(updated.Status.Conditions, "Suspended") != nil {
+		t.Errorf("expected the stale Suspended condition to be removed")
+	}
+	if cond := helpers.FindWorkCondition(updated.Status.Conditions, "InSync"); cond == nil || cond.Status != metav1.ConditionUnknown {
+		t.Errorf("expected the invalid manifest to leave the work unknown, got %+v", cond)
+	}
+	if inSync := testutil.ToFloat64(inSyncManifests.WithLabelValues(work.Namespace, work.Name)); inSync != 1 {
+		t.Errorf("expected 1 resource in sync, got %v", inSync)
+	}
+	if drifted := testutil.ToFloat64(driftedManifests.WithLabelValues(work.Namespace, work.Name)); drifted != 0 {
+		t.Errorf("expected no drifted resource, got %v", drifted)
+	}
+}
+
+func TestReconcileAvailable(t *testing.T) {
+	work := fakeWork("available")
+	r := newFakeWorkReconciler(t, work)
+
+	updated := reconcileWork(t, r, work)
+	for conditionType, status := range map[string]metav1.ConditionStatus{"Applied": metav1.ConditionTrue, "Available": metav1.ConditionTrue, "Degraded": metav1.ConditionFalse} {
+		if cond := helpers.FindWorkCondition(updated.Status.Conditions, conditionType); cond == nil || cond.Status != status {
+			t.Errorf("expected %s to be %s, got %+v", conditionType, status, cond)
+		}
+	}
+	if len(updated.Status.ManifestConditions) != 1 || !helpers.IsConditionTrue(helpers.FindWorkCondition(updated.Status.ManifestConditions[0].Conditions, "Available")) {
+		t.Errorf("expected the manifest to be available, got %+v", updated.Status.ManifestConditions)
+	}
+}
+
+func TestGenerateWorkDegradedCondition(t *testing.T) {
+	now := time.Now()
+	failed := multiclusterv1alpha1.StatusCondition{Type: "Applied", Status: metav1.ConditionFalse, Message: "forbidden"}
+	cases := []struct {
+		name            string
+		previous        *multiclusterv1alpha1.StatusCondition
+		applied         multiclusterv1alpha1.StatusCondition
+		expectedStatus  metav1.ConditionStatus
+		expectedRecheck time.Duration
+	}{
+		{
+			name:           "applied",
+			previous:       &multiclusterv1alpha1.StatusCondition{Type: "Applied", Status: metav1.ConditionFalse, LastTransitionTime: metav1.NewTime(now.Add(-time.Hour))},
+			applied:        multiclusterv1alpha1.StatusCondition{Type: "Applied", Status: metav1.ConditionTrue},
+			expectedStatus: metav1.ConditionFalse,
+		},
+		{
+			name:            "first failure",
+			applied:         failed,
+			expectedStatus:  metav1.ConditionFalse,
+			expectedRecheck: degradedAfter,
+		},
+		{
+			name:            "failing recently",
+			previous:        &multiclusterv1alpha1.StatusCondition{Type: "Applied", Status: metav1.ConditionFalse, LastTransitionTime: metav1.NewTime(now.Add(-time.Minute))},
+			applied:         failed,
+			expectedStatus:  metav1.ConditionFalse,
+			expectedRecheck: degradedAfter - time.Minute,
+		},
+		{
+			name:           "failing for long",
+			previous:       &multiclusterv1alpha1.StatusCondition{Type: "Applied", Status: metav1.ConditionFalse, LastTransitionTime: metav1.NewTime(now.Add(-time.Hour))},
+			applied:        failed,
+			expectedStatus: metav1.ConditionTrue,
+		},
+	}
+
+	for _, c := range cases {
+		cond, recheck := generateWorkDegradedCondition(c.previous, c.applied, now)
+		if cond.Status != c.expectedStatus {
+			t.Errorf("%s: expected Degraded to be %s, got %+v", c.name, c.expectedStatus, cond)
+		}
+		// Transition times are stored with a precision of a second.
+		if recheck < c.expectedRecheck-time.Second || recheck > c.expectedRecheck+time.Second {
+			t.Errorf("%s: expected a recheck after %s, got %s", c.name, c.expectedRecheck, recheck)
+		}
+	}
+}
+
+func TestPollGitWork(t *testing.T) {
+	inline := fakeWork("inline")
+	git := fakeWork("git")
+	git.Spec.Workload.Git = &multiclusterv1alpha1.GitSource{URL: "https://example.com/repo.git", Ref: "main"}
+
+	if result := pollGitWork(inline, ctrl.Result{}); result.RequeueAfter != 0 {
+		t.Errorf("expected inline works not to be polled, got %+v", result)
+	}
+	if result := pollGitWork(git, ctrl.Result{}); result.RequeueAfter != inventory.PollInterval {
+		t.Errorf("expected git works to be polled, got %+v", result)
+	}
+	if result := pollGitWork(git, ctrl.Result{RequeueAfter: time.Second}); result.RequeueAfter != time.Second {
+		t.Errorf("expected the earlier requeue to be kept, got %+v", result)
+	}
+}
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/clusterinfo"
+)
+
+// WorkTemplateReconciler runs on the hub, and places a work created from every work template in the
+// namespaces of the clusters selected by the template. With a rollout strategy, a change of the template
+// is placed in batches of clusters. The conditions of the works are rolled up in the status of the template.
+// A cluster is a namespace holding the ClusterInfo published by its agent, and is selected by the
+// labels of that namespace. The agent can write its ClusterInfo, so its labels are never used for placement.
+type WorkTemplateReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=multicluster.x-k8s.io,resources=worktemplates,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=multicluster.x-k8s.io,resources=worktemplates/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+func (r *WorkTemplateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("worktemplate", req.Name)
+
+	template := &multiclusterv1alpha1.WorkTemplate{}
+	err := r.Get(ctx, req.NamespacedName, template)
+	if err != nil {
+		// Works are owned by the template, so they are garbage collected once it is deleted.
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	clusters, canaries, err := r.selectClusters(ctx, template)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// works holds the work of the template in every selected cluster which has one.
+	works := map[string]*multiclusterv1alpha1.Work{}
+	available := []string{}
+	for _, cluster := range clusters {
+		work := &multiclusterv1alpha1.Work{}
+		err := r.Get(ctx, ktypes.NamespacedName{Namespace: cluster, Name: template.Name}, work)
+		if apierrors.IsNotFound(err) {
+			available = append(available, cluster)
+			continue
+		} else if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !metav1.IsControlledBy(work, template) {
+			log.Info("not placing work over a work which is not owned by the template", "namespace", cluster)
+			continue
+		}
+		works[cluster] = work
+		available = append(available, cluster)
+	}
+
+	toPlace := available
+	template.Status.Rollout = nil
+	if template.Spec.Rollout != nil {
+		toPlace, template.Status.Rollout = planRollout(template, available, canaries, works)
+	}
+	for _, cluster := range toPlace {
+		work, err := r.placeWork(ctx, template, cluster, works[cluster])
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		works[cluster] = work
+	}
+
+	if err := r.pruneWorks(ctx, template, clusters); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	placed := []string{}
+	for _, cluster := range available {
+		if works[cluster] != nil {
+			placed = append(placed, cluster)
+		}
+	}
+	template.Status.ObservedGeneration = template.Generation
+	template.Status.Clusters = placed
+	template.Status.Summary, template.Status.Failures = summarizeWorks(available, works)
+	return ctrl.Result{}, r.Status().Update(ctx, template)
+}
+
+// selectClusters returns the sorted namespaces of the clusters selected by the template,
+// and the canary clusters of its rollout.
+func (r *WorkTemplateReconciler) selectClusters(ctx context.Context, template *multiclusterv1alpha1.WorkTemplate) ([]string, map[string]bool, error) {
+	selector, err := metav1.LabelSelectorAsSelector(template.Spec.ClusterSelector)
+	if err != nil {
+		return nil, nil, err
+	}
+	canarySelector := labels.Nothing()
+	if template.Spec.Rollout != nil && template.Spec.Rollout.CanarySelector != nil {
+		canarySelector, err = metav1.LabelSelectorAsSelector(template.Spec.Rollout.CanarySelector)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	infos := &multiclusterv1alpha1.ClusterInfoList{}
+	if err := r.List(ctx, infos); err != nil {
+		return nil, nil, err
+	}
+	hasClusterInfo := map[string]bool{}
+	for _, info := range infos.Items {
+		if info.Name == clusterinfo.Name {
+			hasClusterInfo[info.Namespace] = true
+		}
+	}
+	namespaces := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaces); err != nil {
+		return nil, nil, err
+	}
+
+	clusters := []string{}
+	canaries := map[string]bool{}
+	for _, namespace := range namespaces.Items {
+		if !hasClusterInfo[namespace.Name] {
+			continue
+		}
+		if selector.Matches(labels.Set(namespace.Labels)) {
+			clusters = append(clusters, namespace.Name)
+			canaries[namespace.Name] = canarySelector.Matches(labels.Set(namespace.Labels))
+		}
+	}
+	sort.Strings(clusters)
+	return clusters, canaries, nil
+}
+
+// placeWork creates the work of the template in a cluster namespace, or updates the existing work
+// to the generation of the template.
+func (r *WorkTemplateReconciler) placeWork(ctx context.Context, template *multiclusterv1alpha1.WorkTemplate, namespace string, work *multiclusterv1alpha1.Work) (*multiclusterv1alpha1.Work, error) {
+	generation := strconv.FormatInt(template.Generation, 10)
+	if work == nil {
+		work = &multiclusterv1alpha1.Work{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        template.Name,
+				Labels:      map[string]string{multiclusterv1alpha1.WorkTemplateLabel: template.Name},
+				Annotations: map[string]string{multiclusterv1alpha1.WorkTemplateGenerationAnnotation: generation},
+			},
+			Spec: *template.Spec.Template.DeepCopy(),
+		}
+		if err := ctrl.SetControllerReference(template, work, r.Scheme); err != nil {
+			return nil, err
+		}
+		return work, r.Create(ctx, work)
+	}
+
+	if equality.Semantic.DeepEqual(defaultedSpec(work.Spec), defaultedSpec(template.Spec.Template)) && work.Annotations[multiclusterv1alpha1.WorkTemplateGenerationAnnotation] == generation {
+		return work, nil
+	}
+	work.Spec = *template.Spec.Template.DeepCopy()
+	if work.Annotations == nil {
+		work.Annotations = map[string]string{}
+	}
+	work.Annotations[multiclusterv1alpha1.WorkTemplateGenerationAnnotation] = generation
+	return work, r.Update(ctx, work)
+}
+
+// defaultedSpec returns the spec as it is stored once admitted, so a work defaulted on admission
+// is not seen as different from its template.
+func defaultedSpec(spec multiclusterv1alpha1.WorkSpec) multiclusterv1alpha1.WorkSpec {
+	defaulted := spec.DeepCopy()
+	multiclusterv1alpha1.DefaultWorkSpec(defaulted)
+	return *defaulted
+}
+
+// pruneWorks deletes the works of the template in the namespaces of clusters which are no longer selected.
+func (r *WorkTemplateReconciler) pruneWorks(ctx context.Context, template *multiclusterv1alpha1.WorkTemplate, clusters []string) error {
+	selected := map[string]bool{}
+	for _, cluster := range clusters {
+		selected[cluster] = true
+	}
+
+	works := &multiclusterv1alpha1.WorkList{}
+	if err := r.List(ctx, works, client.MatchingLabels{multiclusterv1alpha1.WorkTemplateLabel: template.Name}); err != nil {
+		return err
+	}
+	for i := range works.Items {
+		work := &works.Items[i]
+		if selected[work.Namespace] || !metav1.IsControlledBy(work, template) {
+			continue
+		}
+		r.Log.Info("removing work from cluster which is no longer selected", "worktemplate", template.Name, "namespace", work.Namespace)
+		if err := r.Delete(ctx, work); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requestsForAllTemplates enqueues every work template, since a change of a cluster may change their placement.
+func (r *WorkTemplateReconciler) requestsForAllTemplates(handler.MapObject) []ctrl.Request {
+	templates := &multiclusterv1alpha1.WorkTemplateList{}
+	if err := r.List(context.Background(), templates); err != nil {
+		r.Log.Error(err, "unable to list work templates")
+		return nil
+	}
+	requests := []ctrl.Request{}
+	for _, template := range templates.Items {
+		requests = append(requests, ctrl.Request{NamespacedName: ktypes.NamespacedName{Name: template.Name}})
+	}
+	return requests
+}
+
+func (r *WorkTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&multiclusterv1alpha1.WorkTemplate{}).
+		Owns(&multiclusterv1alpha1.Work{}).
+		Watches(&source.Kind{Type: &multiclusterv1alpha1.ClusterInfo{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.requestsForAllTemplates),
+		}).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.requestsForAllTemplates),
+		}).
+		Complete(r)
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/clusterinfo"
+)
+
+func TestWorkTemplatePlacement(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := multiclusterv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	template := &multiclusterv1alpha1.WorkTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", UID: "app-uid", Generation: 1},
+		Spec: multiclusterv1alpha1.WorkTemplateSpec{
+			ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"environment": "prod"}},
+		},
+	}
+	prod := map[string]string{"environment": "prod"}
+	objects := []runtime.Object{
+		template,
+		// east is a cluster whose namespace is selected.
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "east", Labels: prod}},
+		&multiclusterv1alpha1.ClusterInfo{ObjectMeta: metav1.ObjectMeta{Namespace: "east", Name: clusterinfo.Name}},
+		// The agent of west labels its ClusterInfo, which does not select it.
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "west"}},
+		&multiclusterv1alpha1.ClusterInfo{ObjectMeta: metav1.ObjectMeta{Namespace: "west", Name: clusterinfo.Name, Labels: prod}},
+		// other is not a cluster, since no agent published a ClusterInfo.
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: prod}},
+	}
+	r := &WorkTemplateReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme, objects...),
+		Log:    ctrl.Log.WithName("controllers").WithName("WorkTemplate"),
+		Scheme: scheme,
+	}
+	reconcileTemplate := func() []string {
+		if _, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Name: "app"}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		works := &multiclusterv1alpha1.WorkList{}
+		if err := r.List(context.Background(), works, client.MatchingLabels{multiclusterv1alpha1.WorkTemplateLabel: "app"}); err != nil {
+			t.Fatal(err)
+		}
+		namespaces := []string{}
+		for _, work := range works.Items {
+			namespaces = append(namespaces, work.Namespace)
+		}
+		return namespaces
+	}
+
+	if placed := reconcileTemplate(); !reflect.DeepEqual(placed, []string{"east"}) {
+		t.Errorf("expected the work to be placed on east, got %v", placed)
+	}
+
+	// The work is removed once the namespace of the cluster is no longer selected.
+	east := &corev1.Namespace{}
+	if err := r.Get(context.Background(), types.NamespacedName{Name: "east"}, east); err != nil {
+		t.Fatal(err)
+	}
+	east.Labels = nil
+	if err := r.Update(context.Background(), east); err != nil {
+		t.Fatal(err)
+	}
+	if placed := reconcileTemplate(); len(placed) != 0 {
+		t.Errorf("expected the work to be pruned, got %v", placed)
+	}
+}
@@ -0,0 +1,127 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PrivateKeySecretKey is the key in the spoke Secret holding the PEM encoded private key.
+const PrivateKeySecretKey = "key.pem"
+
+// dataKeySize is the size of the AES-256 key each manifest is encrypted with.
+const dataKeySize = 32
+
+// KeySource reads the private key of the spoke cluster from a Secret on the spoke cluster.
+// The Secret is read on every call, so a rotated key is picked up without a restart.
+type KeySource struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewKeySource is to create the key source struct
+func NewKeySource(client kubernetes.Interface, namespace, name string) *KeySource {
+	return &KeySource{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// PrivateKey returns the private key stored in the Secret.
+func (s *KeySource) PrivateKey() (*rsa.PrivateKey, error) {
+	secret, err := s.client.CoreV1().Secrets(s.namespace).Get(context.Background(), s.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	data, found := secret.Data[PrivateKeySecretKey]
+	if !found {
+		return nil, fmt.Errorf("secret %s/%s has no %q key", s.namespace, s.name, PrivateKeySecretKey)
+	}
+	return ParsePrivateKey(data)
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS#1 or PKCS#8 RSA private key.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found in private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.New("failed to parse private key")
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// Encrypt encrypts plaintext with a random AES-256-GCM data key, and encrypts the data key to the public key
+// with RSA-OAEP (SHA-256).
+// The returned ciphertext is prefixed with the GCM nonce.
+func Encrypt(publicKey *rsa.PublicKey, plaintext []byte) (encryptedKey []byte, ciphertext []byte, err error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	encryptedKey, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, dataKey, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encryptedKey, gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt reverses Encrypt.
+// Errors never include any of the decrypted content.
+func Decrypt(privateKey *rsa.PrivateKey, encryptedKey []byte, ciphertext []byte) ([]byte, error) {
+	dataKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, encryptedKey, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt the data key")
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce := ciphertext[:gcm.NonceSize()]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt the ciphertext")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,39 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := []byte(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"s","namespace":"default"}}`)
+	encryptedKey, ciphertext, err := Encrypt(&key.PublicKey, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, err := Decrypt(parsed, encryptedKey, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != string(manifest) {
+		t.Errorf("expected %s, got %s", manifest, plaintext)
+	}
+
+	ciphertext[len(ciphertext)-1] ^= 0xff
+	if _, err := Decrypt(parsed, encryptedKey, ciphertext); err == nil {
+		t.Error("expected tampered ciphertext to fail decryption")
+	}
+}
@@ -0,0 +1,92 @@
+package heartbeat
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LeaseName is the name of the lease renewed by the agent in its hub namespace. It differs from the
+// leader election lease, which may be held in the same namespace.
+const LeaseName = "cluster-reconciler-heartbeat"
+
+const (
+	// DefaultLeaseDuration is the duration after which a lease which is not renewed expires.
+	DefaultLeaseDuration = 60 * time.Second
+	// DefaultRenewInterval is the interval between renewals of the lease.
+	DefaultRenewInterval = 10 * time.Second
+)
+
+// Renewer registers the agent in a hub namespace, by periodically renewing a lease.
+// It implements manager.Runnable, so the lease is only renewed while the manager runs.
+type Renewer struct {
+	Client    kubernetes.Interface
+	Log       logr.Logger
+	Namespace string
+	// Holder identifies the agent in the lease.
+	Holder        string
+	LeaseDuration time.Duration
+	RenewInterval time.Duration
+}
+
+// Start renews the lease until stopCh is closed.
+func (r *Renewer) Start(stopCh <-chan struct{}) error {
+	wait.Until(func() {
+		if err := r.Renew(); err != nil {
+			r.Log.Error(err, "unable to renew heartbeat lease", "namespace", r.Namespace)
+		}
+	}, r.RenewInterval, stopCh)
+	return nil
+}
+
+// Renew creates the lease, or updates its renew time.
+func (r *Renewer) Renew() error {
+	ctx := context.Background()
+	leases := r.Client.CoordinationV1().Leases(r.Namespace)
+	now := metav1.NewMicroTime(time.Now())
+	durationSeconds := int32(r.LeaseDuration / time.Second)
+
+	lease, err := leases.Get(ctx, LeaseName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: r.Namespace,
+				Name:      LeaseName,
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &r.Holder,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != r.Holder {
+		lease.Spec.HolderIdentity = &r.Holder
+		lease.Spec.AcquireTime = &now
+	}
+	lease.Spec.LeaseDurationSeconds = &durationSeconds
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// ExpiresIn returns the duration until the lease expires, it is not positive once the lease has expired.
+// A lease which was never renewed has expired.
+func ExpiresIn(lease *coordinationv1.Lease, now time.Time) time.Duration {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return 0
+	}
+	expiry := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	return expiry.Sub(now)
+}
@@ -0,0 +1,53 @@
+package heartbeat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestRenew(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	renewer := &Renewer{
+		Client:        client,
+		Log:           logf.Log,
+		Namespace:     "cluster1",
+		Holder:        "agent",
+		LeaseDuration: time.Minute,
+		RenewInterval: time.Second,
+	}
+
+	if err := renewer.Renew(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lease, err := client.CoordinationV1().Leases("cluster1").Get(context.Background(), LeaseName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("lease was not created: %v", err)
+	}
+	if expiresIn := ExpiresIn(lease, time.Now()); expiresIn <= 0 || expiresIn > time.Minute {
+		t.Errorf("unexpected expiry of new lease: %v", expiresIn)
+	}
+	if expiresIn := ExpiresIn(lease, time.Now().Add(2*time.Minute)); expiresIn > 0 {
+		t.Errorf("expected lease to expire, expires in %v", expiresIn)
+	}
+
+	firstRenewTime := lease.Spec.RenewTime.Time
+	time.Sleep(10 * time.Millisecond)
+	if err := renewer.Renew(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lease, err = client.CoordinationV1().Leases("cluster1").Get(context.Background(), LeaseName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lease.Spec.RenewTime.After(firstRenewTime) {
+		t.Errorf("renew time was not updated")
+	}
+	if *lease.Spec.HolderIdentity != "agent" {
+		t.Errorf("unexpected holder %s", *lease.Spec.HolderIdentity)
+	}
+}
@@ -42,3 +42,17 @@ func SetWorkCondition(conditions *[]multiclusterv1alpha1.StatusCondition, newCon
 	existingCondition.Reason = newCondition.Reason
 	existingCondition.Message = newCondition.Message
 }
+
+// RemoveWorkCondition removes the condition of conditionType, if any.
+func RemoveWorkCondition(conditions *[]multiclusterv1alpha1.StatusCondition, conditionType string) {
+	if conditions == nil {
+		return
+	}
+	kept := []multiclusterv1alpha1.StatusCondition{}
+	for _, condition := range *conditions {
+		if condition.Type != conditionType {
+			kept = append(kept, condition)
+		}
+	}
+	*conditions = kept
+}
@@ -0,0 +1,140 @@
+package hubconfig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ReloadInterval is how often the kubeconfig file is checked for changes. It could be modified during testing
+var ReloadInterval = 10 * time.Second
+
+// RunFunc runs against the hub until stopCh is closed.
+type RunFunc func(hubConfig *rest.Config, stopCh <-chan struct{}) error
+
+// RunWithReload runs run with the hub config loaded from the kubeconfig at path.
+// When the content of the file or of the certificate, key and token files it references changes, such as when
+// the credentials in a mounted secret are rotated, run is stopped and started again with the new config.
+// It returns once stopCh is closed, or run fails.
+func RunWithReload(log logr.Logger, path string, stopCh <-chan struct{}, run RunFunc) error {
+	for {
+		content, err := fingerprint(path)
+		if err != nil {
+			return err
+		}
+		hubConfig, err := clientcmd.BuildConfigFromFlags("", path)
+		if err != nil {
+			return err
+		}
+
+		runStopCh := make(chan struct{})
+		changed := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-stopCh:
+			case <-changed:
+			case <-done:
+			}
+			close(runStopCh)
+		}()
+		go watch(log, path, content, runStopCh, changed)
+
+		err = run(hubConfig, runStopCh)
+		close(done)
+
+		select {
+		case <-stopCh:
+			return err
+		default:
+		}
+		select {
+		case <-changed:
+			log.Info("hub kubeconfig changed, restarting", "path", path)
+		default:
+			// run returned on its own.
+			return err
+		}
+	}
+}
+
+// watch closes changed once the fingerprint of path differs from content, and path is a valid kubeconfig.
+// It returns when stopCh is closed.
+func watch(log logr.Logger, path string, content []byte, stopCh <-chan struct{}, changed chan struct{}) {
+	ticker := time.NewTicker(ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			current, err := fingerprint(path)
+			if err != nil {
+				// The files may be missing while a secret volume is updated.
+				log.Error(err, "unable to read hub kubeconfig", "path", path)
+				continue
+			}
+			if bytes.Equal(current, content) {
+				continue
+			}
+			if _, err := clientcmd.BuildConfigFromFlags("", path); err != nil {
+				log.Error(err, "invalid hub kubeconfig, keeping the current config", "path", path)
+				continue
+			}
+			close(changed)
+			return
+		}
+	}
+}
+
+// fingerprint hashes the kubeconfig at path along with every certificate, key and token file it references.
+func fingerprint(path string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := clientcmd.Load(content)
+	if err != nil {
+		return nil, err
+	}
+	// Relative paths are relative to the kubeconfig.
+	for _, cluster := range config.Clusters {
+		cluster.LocationOfOrigin = path
+	}
+	for _, authInfo := range config.AuthInfos {
+		authInfo.LocationOfOrigin = path
+	}
+	if err := clientcmd.ResolveLocalPaths(config); err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, cluster := range config.Clusters {
+		files = append(files, cluster.CertificateAuthority)
+	}
+	for _, authInfo := range config.AuthInfos {
+		files = append(files, authInfo.ClientCertificate, authInfo.ClientKey, authInfo.TokenFile)
+	}
+	sort.Strings(files)
+
+	hash := sha256.New()
+	hash.Write(content)
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		referenced, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		hash.Write([]byte(file + "\x00"))
+		hash.Write(referenced)
+	}
+	return hash.Sum(nil), nil
+}
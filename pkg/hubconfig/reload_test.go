@@ -0,0 +1,123 @@
+package hubconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const kubeconfigTemplate = `apiVersion: v1
+kind: Config
+clusters:
+- name: hub
+  cluster:
+    server: %s
+contexts:
+- name: hub
+  context:
+    cluster: hub
+    user: agent
+current-context: hub
+users:
+- name: agent
+  user:
+    token: %s
+`
+
+func TestRunWithReload(t *testing.T) {
+	ReloadInterval = 10 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "hubconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kubeconfig")
+	if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(kubeconfigTemplate, "https://hub:6443", "old")), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := make(chan string, 2)
+	stopCh := make(chan struct{})
+	result := make(chan error)
+	go func() {
+		result <- RunWithReload(logf.Log, path, stopCh, func(hubConfig *rest.Config, runStopCh <-chan struct{}) error {
+			tokens <- hubConfig.BearerToken
+			<-runStopCh
+			return nil
+		})
+	}()
+
+	if token := <-tokens; token != "old" {
+		t.Fatalf("expected token old, got %s", token)
+	}
+	if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(kubeconfigTemplate, "https://hub:6443", "rotated")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case token := <-tokens:
+		if token != "rotated" {
+			t.Fatalf("expected token rotated, got %s", token)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run was not restarted with the rotated credentials")
+	}
+
+	close(stopCh)
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunWithReloadReferencedFiles(t *testing.T) {
+	ReloadInterval = 10 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "hubconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kubeconfig")
+	// The certificate and key are projected next to the kubeconfig, and referenced with relative paths.
+	kubeconfig := strings.Replace(fmt.Sprintf(kubeconfigTemplate, "https://hub:6443", ""), "token: ", "client-certificate: tls.crt\n    client-key: tls.key", 1)
+	for name, content := range map[string]string{"kubeconfig": kubeconfig, "tls.crt": "old certificate", "tls.key": "old key"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	certFiles := make(chan string, 2)
+	stopCh := make(chan struct{})
+	result := make(chan error)
+	go func() {
+		result <- RunWithReload(logf.Log, path, stopCh, func(hubConfig *rest.Config, runStopCh <-chan struct{}) error {
+			certFiles <- hubConfig.CertFile
+			<-runStopCh
+			return nil
+		})
+	}()
+
+	if certFile := <-certFiles; certFile != filepath.Join(dir, "tls.crt") {
+		t.Fatalf("expected the certificate of the kubeconfig, got %s", certFile)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "tls.crt"), []byte("rotated certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-certFiles:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run was not restarted once the certificate was rotated")
+	}
+
+	close(stopCh)
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+}
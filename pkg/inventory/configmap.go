@@ -0,0 +1,84 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/vllry/cluster-reconciler/pkg/parse"
+)
+
+// ConfigMapSource is a source reading the YAML or JSON manifests held in the values of a ConfigMap on the spoke cluster.
+// Values are read in the order of their keys, and their objects have the key as source.
+type ConfigMapSource struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+}
+
+// Fetch reads the manifests, the revision is the resource version of the ConfigMap.
+// A value which fails to parse is reported as a single failed object.
+func (s *ConfigMapSource) Fetch() ([]Object, string, error) {
+	configMap, err := s.Client.CoreV1().ConfigMaps(s.Namespace).Get(context.Background(), s.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	keys := make([]string, 0, len(configMap.Data))
+	for key := range configMap.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	objects := []Object{}
+	for _, key := range keys {
+		objs, err := parse.ParseYaml(configMap.Data[key])
+		if err != nil {
+			objects = append(objects, Object{Err: fmt.Errorf("failed to parse key %s of configmap %s/%s: %v", key, s.Namespace, s.Name, err), Source: key})
+			continue
+		}
+		objects = append(objects, toObjects(key, 0, objs)...)
+	}
+	return objects, configMap.ResourceVersion, nil
+}
+
+// Watch watches the ConfigMap, and reconnects once the watch ends.
+func (s *ConfigMapSource) Watch(stopCh <-chan struct{}) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	go func() {
+		defer close(changed)
+		for {
+			watcher, err := s.Client.CoreV1().ConfigMaps(s.Namespace).Watch(context.Background(), metav1.ListOptions{
+				FieldSelector: fields.OneTermEqualSelector("metadata.name", s.Name).String(),
+			})
+			if err != nil {
+				select {
+				case <-stopCh:
+					return
+				case <-time.After(PollInterval):
+					continue
+				}
+			}
+
+		events:
+			for {
+				select {
+				case <-stopCh:
+					watcher.Stop()
+					return
+				case _, ok := <-watcher.ResultChan():
+					if !ok {
+						break events
+					}
+					notify(changed)
+				}
+			}
+		}
+	}()
+	return changed
+}
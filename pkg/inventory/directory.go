@@ -0,0 +1,89 @@
+package inventory
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/vllry/cluster-reconciler/pkg/parse"
+)
+
+// manifestExtensions are the extensions of the files read from a directory.
+var manifestExtensions = map[string]bool{
+	".yaml": true,
+	".yml":  true,
+	".json": true,
+}
+
+// DirectorySource is a source reading a YAML or JSON file, or every such file under a directory.
+type DirectorySource struct {
+	Path string
+}
+
+// Fetch reads the manifests, the revision identifies the paths and content of the files.
+func (s *DirectorySource) Fetch() ([]Object, string, error) {
+	objs, revision, err := readDirectory(s.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	return toObjects("", 0, objs), revision, nil
+}
+
+// Watch polls the files for changes.
+func (s *DirectorySource) Watch(stopCh <-chan struct{}) <-chan struct{} {
+	return pollWatch(stopCh, func() (string, error) {
+		_, revision, err := readDirectory(s.Path)
+		return revision, err
+	})
+}
+
+// ReadDirectory parses every YAML or JSON file under dir, in the lexical order of their paths.
+// Hidden files and directories, such as .git, are skipped. dir may also be a single file.
+func ReadDirectory(dir string) ([]unstructured.Unstructured, error) {
+	objects, _, err := readDirectory(dir)
+	return objects, err
+}
+
+// readDirectory parses the files like ReadDirectory, and returns a revision of their paths and content.
+func readDirectory(dir string) ([]unstructured.Unstructured, string, error) {
+	objects := []unstructured.Unstructured{}
+	hash := sha256.New()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || (path != dir && !manifestExtensions[strings.ToLower(filepath.Ext(path))]) {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		fmt.Fprintf(hash, "%s\x00%d\x00", path, info.Size())
+
+		// The file is hashed as it is parsed, so large files are not held in memory.
+		fileObjects, err := parse.Parse(io.TeeReader(file, hash))
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		objects = append(objects, fileObjects...)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return objects, fmt.Sprintf("%x", hash.Sum(nil))[:16], nil
+}
@@ -0,0 +1,255 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DefaultGitBinaryPath is the git binary used when none is configured.
+const DefaultGitBinaryPath = "git"
+
+// DefaultGitTimeout is the longest a git command may run when no timeout is configured, so an unresponsive
+// remote does not hold the repository forever.
+const DefaultGitTimeout = 2 * time.Minute
+
+// GitRepository reads manifests from a clone of a git repository. The clone is kept in a temporary
+// directory, and fetched again on every read.
+type GitRepository struct {
+	// URL is the URL of the repository, such as file:///repos/manifests.git.
+	URL string
+	// BinaryPath is the path of the git binary, it is looked up in PATH if it is not absolute.
+	BinaryPath string
+	// Timeout bounds every git command.
+	Timeout time.Duration
+
+	lock sync.Mutex
+	dir  string
+}
+
+// NewGitRepository is to create the git repository struct
+func NewGitRepository(url, binaryPath string) *GitRepository {
+	if binaryPath == "" {
+		binaryPath = DefaultGitBinaryPath
+	}
+	return &GitRepository{
+		URL:        url,
+		BinaryPath: binaryPath,
+		Timeout:    DefaultGitTimeout,
+	}
+}
+
+// Read fetches the repository, and parses every YAML or JSON file under path at ref.
+// ref is a branch, a tag or a commit, the default branch is used if it is empty.
+// The commit which was read is returned alongside the objects.
+func (r *GitRepository) Read(ref, path string) ([]unstructured.Unstructured, string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	commit, err := r.fetch(ref)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := r.git(r.dir, "checkout", "-q", "--force", "--detach", commit); err != nil {
+		return nil, "", err
+	}
+
+	dir, err := resolvePath(r.dir, path)
+	if err != nil {
+		return nil, "", err
+	}
+	objects, err := ReadDirectory(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	return objects, commit, nil
+}
+
+// Revision fetches the repository, and returns the commit of ref.
+func (r *GitRepository) Revision(ref string) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.fetch(ref)
+}
+
+// fetch clones or fetches the repository, and returns the commit of ref.
+func (r *GitRepository) fetch(ref string) (string, error) {
+	if r.dir == "" {
+		dir, err := ioutil.TempDir("", "git-source")
+		if err != nil {
+			return "", err
+		}
+		if _, err := r.git(dir, "init", "-q"); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		if _, err := r.git(dir, "remote", "add", "origin", r.URL); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		r.dir = dir
+	}
+
+	if _, err := r.git(r.dir, "fetch", "-q", "--force", "--tags", "--prune", "origin",
+		"+HEAD:refs/remotes/origin/HEAD", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+		return "", err
+	}
+	return r.resolveRef(ref)
+}
+
+// resolveRef returns the commit of a branch, tag or commit of the fetched repository.
+func (r *GitRepository) resolveRef(ref string) (string, error) {
+	candidates := []string{"refs/remotes/origin/HEAD"}
+	if ref != "" {
+		candidates = []string{"refs/remotes/origin/" + ref, "refs/tags/" + ref, ref}
+	}
+	for _, candidate := range candidates {
+		commit, err := r.git(r.dir, "rev-parse", "--verify", "-q", candidate+"^{commit}")
+		if err == nil {
+			return commit, nil
+		}
+	}
+	return "", fmt.Errorf("ref %q is not found in %s", ref, r.URL)
+}
+
+// git runs git in dir, and returns its trimmed output. The command is killed once the timeout expires.
+func (r *GitRepository) git(dir string, args ...string) (string, error) {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultGitTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.BinaryPath, append([]string{"-C", dir}, args...)...)
+	// Never prompt for credentials.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("git %s did not complete within %s", args[0], timeout)
+		}
+		return "", errors.Wrap(err, fmt.Sprintf("git %s failed: %s", args[0], strings.TrimSpace(stderr.String())))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Close removes the clone.
+func (r *GitRepository) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.dir == "" {
+		return nil
+	}
+	err := os.RemoveAll(r.dir)
+	r.dir = ""
+	return err
+}
+
+// resolvePath returns the location of path inside root.
+// Absolute paths, and paths which would escape root, are rejected.
+func resolvePath(root, path string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(path))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid repository path %q", path)
+	}
+	return filepath.Join(root, cleaned), nil
+}
+
+// GitRepositories holds a clone of every repository which was read, so repositories are only fetched
+// incrementally.
+type GitRepositories struct {
+	BinaryPath string
+
+	lock         sync.Mutex
+	repositories map[string]*GitRepository
+}
+
+// NewGitRepositories is to create the git repositories struct
+func NewGitRepositories(binaryPath string) *GitRepositories {
+	return &GitRepositories{
+		BinaryPath:   binaryPath,
+		repositories: map[string]*GitRepository{},
+	}
+}
+
+// Repository returns the repository of url.
+func (g *GitRepositories) Repository(url string) *GitRepository {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if repository, found := g.repositories[url]; found {
+		return repository
+	}
+	repository := NewGitRepository(url, g.BinaryPath)
+	g.repositories[url] = repository
+	return repository
+}
+
+// Close removes the clone of every repository.
+func (g *GitRepositories) Close() error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	var lastErr error
+	for url, repository := range g.repositories {
+		if err := repository.Close(); err != nil {
+			lastErr = err
+		}
+		delete(g.repositories, url)
+	}
+	return lastErr
+}
+
+// GitSource is a source reading the manifests under a path of a git repository at a ref, and tracks the
+// last synced commit.
+type GitSource struct {
+	Repository *GitRepository
+	Ref        string
+	Path       string
+	Log        logr.Logger
+
+	lock             sync.Mutex
+	lastSyncedCommit string
+}
+
+// Fetch reads the manifests at the current commit of the ref, the revision is the commit.
+func (s *GitSource) Fetch() ([]Object, string, error) {
+	objs, commit, err := s.Repository.Read(s.Ref, s.Path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if commit != s.lastSyncedCommit {
+		s.Log.Info("synced git commit", "url", s.Repository.URL, "ref", s.Ref, "path", s.Path, "commit", commit)
+		s.lastSyncedCommit = commit
+	}
+	return toObjects("", 0, objs), commit, nil
+}
+
+// Watch polls the commit of the ref.
+func (s *GitSource) Watch(stopCh <-chan struct{}) <-chan struct{} {
+	return pollWatch(stopCh, func() (string, error) {
+		return s.Repository.Revision(s.Ref)
+	})
+}
+
+// LastSyncedCommit returns the commit of the last successful fetch.
+func (s *GitSource) LastSyncedCommit() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lastSyncedCommit
+}
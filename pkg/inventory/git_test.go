@@ -0,0 +1,126 @@
+package inventory
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const configMapTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s
+`
+
+// commitFile writes a file in the repository at dir, commits it, and returns the commit.
+func commitFile(t *testing.T, dir, path, content string) string {
+	if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, path), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", "update "+path)
+	return runGit(t, dir, "rev-parse", "HEAD")
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func names(t *testing.T, source *GitSource) []string {
+	objects, commit, err := source.Fetch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if commit != source.LastSyncedCommit() {
+		t.Errorf("expected revision %s to be the last synced commit %s", commit, source.LastSyncedCommit())
+	}
+	names := []string{}
+	for _, obj := range objects {
+		names = append(names, obj.Unstructured.GetName())
+	}
+	return names
+}
+
+func TestGitSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "git-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "checkout", "-q", "-b", "main")
+	first := commitFile(t, dir, "manifests/a.yaml", strings.Replace(configMapTemplate, "%s", "a", 1))
+	runGit(t, dir, "tag", "v1")
+	second := commitFile(t, dir, "manifests/b.yaml", strings.Replace(configMapTemplate, "%s", "b", 1))
+
+	repository := NewGitRepository("file://"+dir, "")
+	defer repository.Close()
+	source := &GitSource{Repository: repository, Path: "manifests", Log: logf.Log}
+
+	if got := names(t, source); len(got) != 2 {
+		t.Errorf("expected a and b on the default branch, got %v", got)
+	}
+	if source.LastSyncedCommit() != second {
+		t.Errorf("expected commit %s, got %s", second, source.LastSyncedCommit())
+	}
+
+	source.Ref = "v1"
+	if got := names(t, source); len(got) != 1 || got[0] != "a" {
+		t.Errorf("expected a at the tag, got %v", got)
+	}
+	if source.LastSyncedCommit() != first {
+		t.Errorf("expected commit %s, got %s", first, source.LastSyncedCommit())
+	}
+
+	source.Ref = "main"
+	third := commitFile(t, dir, "manifests/c.yaml", strings.Replace(configMapTemplate, "%s", "c", 1))
+	if got := names(t, source); len(got) != 3 {
+		t.Errorf("expected the new commit to be fetched, got %v", got)
+	}
+	if source.LastSyncedCommit() != third {
+		t.Errorf("expected commit %s, got %s", third, source.LastSyncedCommit())
+	}
+
+	if _, _, err := repository.Read("main", "../outside"); err == nil {
+		t.Errorf("expected a path outside the repository to be rejected")
+	}
+	if _, _, err := repository.Read("missing", ""); err == nil {
+		t.Errorf("expected a missing ref to fail")
+	}
+}
+
+func TestGitTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fake-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	binary := filepath.Join(dir, "git")
+	if err := ioutil.WriteFile(binary, []byte("#!/bin/sh\nexec sleep 10\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	repository := NewGitRepository("https://example.com/repo.git", binary)
+	repository.Timeout = 100 * time.Millisecond
+	defer repository.Close()
+	if _, err := repository.Revision(""); err == nil || !strings.Contains(err.Error(), "did not complete") {
+		t.Errorf("expected git to time out, got %v", err)
+	}
+}
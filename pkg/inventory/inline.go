@@ -0,0 +1,60 @@
+package inventory
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/vllry/cluster-reconciler/pkg/parse"
+)
+
+// InlineSource is a source holding YAML or JSON manifests in memory.
+type InlineSource struct {
+	Content string
+}
+
+// Fetch parses the manifests, the revision identifies the content.
+func (s *InlineSource) Fetch() ([]Object, string, error) {
+	objs, err := parse.ParseYaml(s.Content)
+	if err != nil {
+		return nil, "", err
+	}
+	return toObjects("", 0, objs), contentRevision([]byte(s.Content)), nil
+}
+
+// Watch never receives, since the content does not change.
+func (s *InlineSource) Watch(stopCh <-chan struct{}) <-chan struct{} {
+	return neverChanged(stopCh)
+}
+
+// ReaderSource is a source reading YAML or JSON manifests from a stream, such as stdin.
+// The stream is read by the first fetch, later fetches return the same objects.
+type ReaderSource struct {
+	Reader io.Reader
+
+	once     sync.Once
+	objects  []Object
+	revision string
+	err      error
+}
+
+// Fetch parses the manifests as they are read, the revision identifies the content.
+func (s *ReaderSource) Fetch() ([]Object, string, error) {
+	s.once.Do(func() {
+		hash := sha256.New()
+		objs, err := parse.Parse(io.TeeReader(s.Reader, hash))
+		if err != nil {
+			s.err = err
+			return
+		}
+		s.objects = toObjects("", 0, objs)
+		s.revision = fmt.Sprintf("%x", hash.Sum(nil))[:16]
+	})
+	return s.objects, s.revision, s.err
+}
+
+// Watch never receives, since the stream is only read once.
+func (s *ReaderSource) Watch(stopCh <-chan struct{}) <-chan struct{} {
+	return neverChanged(stopCh)
+}
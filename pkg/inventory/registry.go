@@ -0,0 +1,108 @@
+package inventory
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Registry composes named sources into a single source. Objects are ordered by the registration
+// order of their sources, and the name of their source is prefixed to their own source, as name/source.
+type Registry struct {
+	lock    sync.RWMutex
+	names   []string
+	sources map[string]Source
+}
+
+// NewRegistry is to create the registry struct
+func NewRegistry() *Registry {
+	return &Registry{
+		sources: map[string]Source{},
+	}
+}
+
+// Register adds a source, names must be unique.
+func (r *Registry) Register(name string, source Source) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, found := r.sources[name]; found {
+		return fmt.Errorf("source %q is already registered", name)
+	}
+	r.names = append(r.names, name)
+	r.sources[name] = source
+	return nil
+}
+
+// Unregister removes a source.
+func (r *Registry) Unregister(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, found := r.sources[name]; !found {
+		return
+	}
+	delete(r.sources, name)
+	for i := range r.names {
+		if r.names[i] == name {
+			r.names = append(r.names[:i], r.names[i+1:]...)
+			break
+		}
+	}
+}
+
+// Source returns a registered source.
+func (r *Registry) Source(name string) (Source, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	source, found := r.sources[name]
+	return source, found
+}
+
+// Fetch reads every source. The revision lists the revision of every source, as name=revision.
+func (r *Registry) Fetch() ([]Object, string, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	objects := []Object{}
+	revisions := make([]string, 0, len(r.names))
+	for _, name := range r.names {
+		objs, revision, err := r.sources[name].Fetch()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to fetch source %q: %v", name, err)
+		}
+		for _, obj := range objs {
+			obj.Source = strings.TrimSuffix(name+"/"+obj.Source, "/")
+			objects = append(objects, obj)
+		}
+		revisions = append(revisions, name+"="+revision)
+	}
+	return objects, strings.Join(revisions, ","), nil
+}
+
+// Watch receives whenever any source may have changed.
+// Only the sources registered when Watch is called are watched.
+func (r *Registry) Watch(stopCh <-chan struct{}) <-chan struct{} {
+	r.lock.RLock()
+	watches := make([]<-chan struct{}, 0, len(r.names))
+	for _, name := range r.names {
+		watches = append(watches, r.sources[name].Watch(stopCh))
+	}
+	r.lock.RUnlock()
+
+	changed := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	for _, watch := range watches {
+		wg.Add(1)
+		go func(watch <-chan struct{}) {
+			defer wg.Done()
+			for range watch {
+				notify(changed)
+			}
+		}(watch)
+	}
+	go func() {
+		<-stopCh
+		wg.Wait()
+		close(changed)
+	}()
+	return changed
+}
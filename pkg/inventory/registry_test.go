@@ -0,0 +1,50 @@
+package inventory
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	for _, name := range []string{"b", "a"} {
+		source := &InlineSource{Content: fmt.Sprintf(configMapTemplate, name)}
+		if err := registry.Register(name, source); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := registry.Register("a", &InlineSource{}); err == nil {
+		t.Errorf("expected an error registering a duplicate source")
+	}
+
+	objects, revision, err := registry.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects[0].Unstructured.GetName() != "b" || objects[1].Unstructured.GetName() != "a" {
+		t.Errorf("expected the objects of b then a, got %v", objects)
+	}
+	b, _ := registry.Source("b")
+	_, bRevision, _ := b.Fetch()
+	a, _ := registry.Source("a")
+	_, aRevision, _ := a.Fetch()
+	if expected := "b=" + bRevision + ",a=" + aRevision; revision != expected {
+		t.Errorf("expected revision %q, got %q", expected, revision)
+	}
+
+	registry.Unregister("b")
+	objects, _, err = registry.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Unstructured.GetName() != "a" {
+		t.Errorf("expected the objects of a, got %v", objects)
+	}
+
+	stopCh := make(chan struct{})
+	changed := registry.Watch(stopCh)
+	close(stopCh)
+	if _, ok := <-changed; ok {
+		t.Errorf("expected the watch to be closed once stopped")
+	}
+}
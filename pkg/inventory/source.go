@@ -0,0 +1,147 @@
+package inventory
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/vllry/cluster-reconciler/pkg/reconcile"
+	"github.com/vllry/cluster-reconciler/pkg/restmapper"
+	"github.com/vllry/cluster-reconciler/pkg/types"
+)
+
+// PollInterval is the interval at which sources which cannot be watched are checked for changes.
+// It could be modified during testing
+var PollInterval = 30 * time.Second
+
+// Source is an inventory of desired objects.
+type Source interface {
+	// Fetch returns the objects of the source, and the revision they were read at.
+	// Objects which cannot be read carry the error, so they can be reported at their position.
+	Fetch() ([]Object, string, error)
+	// Watch returns a channel which receives whenever the source may have changed. The channel is
+	// closed once stopCh is closed.
+	Watch(stopCh <-chan struct{}) <-chan struct{}
+}
+
+// Object is an object of a source, or the error which prevented reading it.
+type Object struct {
+	Unstructured unstructured.Unstructured
+	// Err is set when the object could not be read, and explains why.
+	Err error
+	// Sensitive marks objects whose content must not be surfaced, such as in error messages.
+	Sensitive bool
+
+	// Source is the part of the source holding the object, such as the encrypted manifests of a work.
+	// It is empty for the manifests of a work.
+	Source string
+	// Index is the index of the manifest holding the object in Source.
+	Index int
+	// Item is the index of the object among the objects of its manifest, such as the items of a List.
+	Item int
+}
+
+// ResolveFunc converts an object to a Semistructured object.
+type ResolveFunc func(obj unstructured.Unstructured) types.Semistructured
+
+// Resolver returns a ResolveFunc mapping the resource of objects with mapper.
+// Namespaced objects without a namespace are placed in the default namespace, like kubectl does.
+func Resolver(mapper *restmapper.Mapper) ResolveFunc {
+	return func(obj unstructured.Unstructured) types.Semistructured {
+		semi, err := types.UnstructuredToSemistructured(obj)
+		if err != nil {
+			semi.Err = err
+			return semi
+		}
+		mapping, err := mapper.MappingForGVK(semi.Identifier.GroupVersionKind)
+		if err != nil {
+			semi.Err = err
+			return semi
+		}
+		semi.Identifier.GroupVersionResource = mapping.Resource
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace && semi.Identifier.NamespacedName.Namespace == "" {
+			semi.Identifier.NamespacedName.Namespace = metav1.NamespaceDefault
+			semi.Unstructured.SetNamespace(metav1.NamespaceDefault)
+		}
+		return semi
+	}
+}
+
+// FetchFunc returns a reconcile.FetchDesiredObjectFunc fetching the objects of source, and resolving them with resolve.
+// Objects which cannot be read or resolved are identified by their source, manifest index and item, which are unique.
+func FetchFunc(source Source, resolve ResolveFunc) reconcile.FetchDesiredObjectFunc {
+	return func() (map[types.ResourceIdentifier]types.Semistructured, error) {
+		objects, _, err := source.Fetch()
+		if err != nil {
+			return nil, err
+		}
+		desired := map[types.ResourceIdentifier]types.Semistructured{}
+		for _, obj := range objects {
+			semi := types.Semistructured{Err: obj.Err}
+			if obj.Err == nil {
+				semi = resolve(obj.Unstructured)
+			}
+			if semi.Err != nil {
+				semi.Identifier.Source, semi.Identifier.Ordinal, semi.Identifier.Item = obj.Source, obj.Index, obj.Item
+			}
+			semi.Sensitive = obj.Sensitive
+			desired[semi.Identifier] = semi
+		}
+		return desired, nil
+	}
+}
+
+// toObjects wraps the objects of the manifest at index in source, which were read successfully.
+func toObjects(source string, index int, objs []unstructured.Unstructured) []Object {
+	objects := make([]Object, 0, len(objs))
+	for item, obj := range objs {
+		objects = append(objects, Object{Unstructured: obj, Source: source, Index: index, Item: item})
+	}
+	return objects
+}
+
+// contentRevision returns a revision identifying content.
+func contentRevision(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))[:16]
+}
+
+// pollWatch returns a channel which receives whenever the revision changes, checking it every PollInterval.
+func pollWatch(stopCh <-chan struct{}, revision func() (string, error)) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	go func() {
+		defer close(changed)
+		last, _ := revision()
+		wait.Until(func() {
+			current, err := revision()
+			if err != nil || current == last {
+				return
+			}
+			last = current
+			notify(changed)
+		}, PollInterval, stopCh)
+	}()
+	return changed
+}
+
+// notify sends on changed without blocking, a pending notification already covers the change.
+func notify(changed chan struct{}) {
+	select {
+	case changed <- struct{}{}:
+	default:
+	}
+}
+
+// neverChanged returns a channel which is closed once stopCh is closed, for sources which do not change.
+func neverChanged(stopCh <-chan struct{}) <-chan struct{} {
+	changed := make(chan struct{})
+	go func() {
+		<-stopCh
+		close(changed)
+	}()
+	return changed
+}
@@ -0,0 +1,174 @@
+package inventory
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"fmt"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/encryption"
+	"github.com/vllry/cluster-reconciler/pkg/kustomize"
+	"github.com/vllry/cluster-reconciler/pkg/parse"
+	"github.com/vllry/cluster-reconciler/pkg/restmapper"
+)
+
+// Sources of the objects of a workload other than its manifests, which have no source.
+const (
+	SourceEncryptedManifests = "encryptedManifests"
+	SourceKustomization      = "kustomization"
+	SourceGit                = "git"
+)
+
+// WorkSource is a source reading the workload of a work fetched from the hub.
+// Objects are ordered as manifests, encrypted manifests, the rendered kustomization, then the git source.
+// Manifests holding a list are flattened into its items, which keep the index of their manifest.
+type WorkSource struct {
+	Work *multiclusterv1alpha1.Work
+	// Kustomize renders the kustomization of the workload.
+	Kustomize *kustomize.Renderer
+	// KeySource holds the private key decrypting encrypted manifests.
+	KeySource *encryption.KeySource
+	// Git holds the clones of git sources.
+	Git *GitRepositories
+	// Mapper resolves the scope of manifests when the defaults of the work are expanded.
+	Mapper *restmapper.Mapper
+
+	lastSyncedCommit string
+}
+
+// ManifestError is the failure to parse the manifest of a work at Index.
+type ManifestError struct {
+	Index int
+	Err   error
+}
+
+func (e *ManifestError) Error() string {
+	return fmt.Sprintf("manifest %d is invalid, %v", e.Index, e.Err)
+}
+
+func (e *ManifestError) Unwrap() error {
+	return e.Err
+}
+
+// Fetch reads the workload. The revision is the generation of the work, followed by the commit of its git source.
+func (s *WorkSource) Fetch() ([]Object, string, error) {
+	workload := s.Work.Spec.Workload
+	revision := strconv.FormatInt(s.Work.Generation, 10)
+
+	objects := []Object{}
+	for index, manifest := range workload.Manifests {
+		objs, err := parse.Parse(bytes.NewReader(manifest.Raw))
+		if err != nil {
+			objects = append(objects, Object{Err: &ManifestError{Index: index, Err: err}, Index: index})
+			continue
+		}
+		if defaults := s.Work.Spec.Defaults; defaults != nil {
+			for i := range objs {
+				multiclusterv1alpha1.DefaultObject(&objs[i], *defaults, s.namespaced)
+			}
+		}
+		objects = append(objects, toObjects("", index, objs)...)
+	}
+
+	if len(workload.EncryptedManifests) > 0 {
+		objects = append(objects, s.decryptManifests(workload.EncryptedManifests)...)
+	}
+
+	if kustomization := workload.Kustomization; kustomization != nil {
+		files := map[string]string{}
+		for _, file := range kustomization.Files {
+			files[file.Path] = file.Content
+		}
+		rendered, err := s.Kustomize.Render(files, kustomization.Path)
+		if err != nil {
+			// The failure is reported on the kustomization, the other objects are still applied.
+			objects = append(objects, Object{Err: fmt.Errorf("failed to render the kustomization: %v", err), Source: SourceKustomization})
+		} else {
+			objects = append(objects, toObjects(SourceKustomization, 0, rendered)...)
+		}
+	}
+
+	if gitSource := workload.Git; gitSource != nil {
+		// The failure is reported on the git source, the other objects are still applied.
+		if err := multiclusterv1alpha1.ValidateGitURL(gitSource.URL); err != nil {
+			objects = append(objects, Object{Err: fmt.Errorf("invalid git url %q, %v", gitSource.URL, err), Source: SourceGit})
+		} else if objs, commit, err := s.Git.Repository(gitSource.URL).Read(gitSource.Ref, gitSource.Path); err != nil {
+			objects = append(objects, Object{Err: fmt.Errorf("failed to read the git source: %v", err), Source: SourceGit})
+		} else {
+			s.lastSyncedCommit = commit
+			revision = revision + "/" + commit
+			objects = append(objects, toObjects(SourceGit, 0, objs)...)
+		}
+	}
+	return objects, revision, nil
+}
+
+// Watch polls the commit of the git source, other changes of the work are watched by the work controller.
+func (s *WorkSource) Watch(stopCh <-chan struct{}) <-chan struct{} {
+	gitSource := s.Work.Spec.Workload.Git
+	if gitSource == nil || multiclusterv1alpha1.ValidateGitURL(gitSource.URL) != nil {
+		return neverChanged(stopCh)
+	}
+	return pollWatch(stopCh, func() (string, error) {
+		return s.Git.Repository(gitSource.URL).Revision(gitSource.Ref)
+	})
+}
+
+// namespaced returns true if the kind is namespaced on the spoke cluster. The defaults of signed works and of
+// kinds unknown to the hub are only expanded here, so the namespace of a kind which cannot be resolved is left unset.
+func (s *WorkSource) namespaced(gvk schema.GroupVersionKind) bool {
+	if s.Mapper == nil {
+		return false
+	}
+	namespaced, err := s.Mapper.Namespaced(gvk)
+	return err == nil && namespaced
+}
+
+// LastSyncedCommit returns the commit of the git source read by the last successful fetch.
+func (s *WorkSource) LastSyncedCommit() string {
+	return s.lastSyncedCommit
+}
+
+// decryptManifests decrypts the encrypted manifests with the private key of the spoke cluster.
+// The decrypted objects are marked as sensitive, and failures are recorded per manifest.
+func (s *WorkSource) decryptManifests(manifests []multiclusterv1alpha1.EncryptedManifest) []Object {
+	var privateKey *rsa.PrivateKey
+	keyErr := fmt.Errorf("no decryption key is configured for the spoke cluster")
+	if s.KeySource != nil {
+		privateKey, keyErr = s.KeySource.PrivateKey()
+	}
+
+	objects := []Object{}
+	for index, manifest := range manifests {
+		failed := Object{Sensitive: true, Source: SourceEncryptedManifests, Index: index}
+		if keyErr != nil {
+			failed.Err = keyErr
+			objects = append(objects, failed)
+			continue
+		}
+		plaintext, err := encryption.Decrypt(privateKey, manifest.EncryptedKey, manifest.Ciphertext)
+		if err != nil {
+			failed.Err = err
+			objects = append(objects, failed)
+			continue
+		}
+		objs, err := parse.Parse(bytes.NewReader(plaintext))
+		if err != nil {
+			// The decoder error may quote the plaintext, only where it was found is kept.
+			redacted := fmt.Errorf("decrypted manifest is not a valid object")
+			if parseErr, ok := err.(*parse.Error); ok {
+				redacted = fmt.Errorf("decrypted manifest is not a valid object, at %s", parseErr.Location())
+			}
+			failed.Err = redacted
+			objects = append(objects, failed)
+			continue
+		}
+		for item, obj := range objs {
+			objects = append(objects, Object{Unstructured: obj, Sensitive: true, Source: SourceEncryptedManifests, Index: index, Item: item})
+		}
+	}
+	return objects
+}
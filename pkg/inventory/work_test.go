@@ -0,0 +1,123 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/kustomize"
+	"github.com/vllry/cluster-reconciler/pkg/restmapper"
+	"github.com/vllry/cluster-reconciler/pkg/types"
+)
+
+func TestWorkSourceKustomizationFailure(t *testing.T) {
+	work := &multiclusterv1alpha1.Work{
+		Spec: multiclusterv1alpha1.WorkSpec{
+			Workload: multiclusterv1alpha1.WorkloadTemplate{
+				Manifests: []multiclusterv1alpha1.Manifest{
+					{RawExtension: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"}}`)}},
+				},
+				Kustomization: &multiclusterv1alpha1.Kustomization{
+					Files: []multiclusterv1alpha1.KustomizationFile{{Path: "kustomization.yaml", Content: "resources: []\n"}},
+				},
+			},
+		},
+	}
+	source := &WorkSource{Work: work, Kustomize: kustomize.NewRenderer("/nonexistent/kustomize")}
+
+	objects, _, err := source.Fetch()
+	if err != nil {
+		t.Fatalf("expected the failure to be reported on the kustomization, got %v", err)
+	}
+	if len(objects) != 2 || objects[0].Err != nil || objects[0].Unstructured.GetName() != "cm" {
+		t.Fatalf("expected the manifest to be read, got %+v", objects)
+	}
+	if objects[1].Err == nil || !strings.Contains(objects[1].Err.Error(), "kustomization") {
+		t.Errorf("expected the render failure on the kustomization, got %v", objects[1].Err)
+	}
+}
+
+func TestWorkSourceDefaults(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "ClusterWidget"}, meta.RESTScopeRoot)
+	work := &multiclusterv1alpha1.Work{
+		Spec: multiclusterv1alpha1.WorkSpec{
+			Workload: multiclusterv1alpha1.WorkloadTemplate{
+				Manifests: []multiclusterv1alpha1.Manifest{
+					{RawExtension: runtime.RawExtension{Raw: []byte(`{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"w"}}`)}},
+					{RawExtension: runtime.RawExtension{Raw: []byte(`{"apiVersion":"example.com/v1","kind":"ClusterWidget","metadata":{"name":"cw"}}`)}},
+				},
+			},
+			Defaults: &multiclusterv1alpha1.WorkloadDefaults{Namespace: "apps", Labels: map[string]string{"env": "prod"}},
+		},
+	}
+	source := &WorkSource{Work: work, Mapper: &restmapper.Mapper{Mapper: mapper}}
+
+	objects, _, err := source.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("expected 2 objects, got %+v", objects)
+	}
+	if objects[0].Unstructured.GetNamespace() != "apps" || objects[1].Unstructured.GetNamespace() != "" {
+		t.Errorf("expected the namespace to be set on the namespaced kind only, got %q and %q", objects[0].Unstructured.GetNamespace(), objects[1].Unstructured.GetNamespace())
+	}
+	for _, obj := range objects {
+		if obj.Unstructured.GetLabels()["env"] != "prod" || obj.Unstructured.GetAnnotations()[types.ManagedAnnotationKey] != types.ManagedAnnotationValue {
+			t.Errorf("expected the defaults to be expanded into %s, got %v", obj.Unstructured.GetName(), obj.Unstructured.Object)
+		}
+	}
+}
+
+func TestWorkSourceFailurePositions(t *testing.T) {
+	work := &multiclusterv1alpha1.Work{
+		Spec: multiclusterv1alpha1.WorkSpec{
+			Workload: multiclusterv1alpha1.WorkloadTemplate{
+				Manifests: []multiclusterv1alpha1.Manifest{
+					{RawExtension: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"List","items":[` +
+						`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a"}},{"apiVersion":"v1","kind":"ConfigMap","metadata":{}}]}`)}},
+					{RawExtension: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","metadata":{"name":"c"}}`)}},
+				},
+				EncryptedManifests: []multiclusterv1alpha1.EncryptedManifest{{}},
+			},
+		},
+	}
+	resolve := func(obj unstructured.Unstructured) types.Semistructured {
+		semi, err := types.UnstructuredToSemistructured(obj)
+		semi.Err = err
+		return semi
+	}
+
+	desired, err := FetchFunc(&WorkSource{Work: work}, resolve)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(desired) != 4 {
+		t.Fatalf("expected every object and failure to be kept, got %+v", desired)
+	}
+	// Failures are identified by where they are in the workload, so they do not collide.
+	cases := []struct {
+		identifier types.ResourceIdentifier
+		message    string
+	}{
+		{identifier: types.ResourceIdentifier{Ordinal: 0, Item: 1}, message: "missing object name"},
+		{identifier: types.ResourceIdentifier{Ordinal: 1}, message: "manifest 1"},
+		{identifier: types.ResourceIdentifier{Source: SourceEncryptedManifests, Ordinal: 0}, message: "no decryption key"},
+	}
+	for _, c := range cases {
+		failed, found := desired[c.identifier]
+		if !found || failed.Err == nil || !strings.Contains(failed.Err.Error(), c.message) {
+			t.Errorf("expected %+v to fail with %q, got %+v", c.identifier, c.message, failed)
+		}
+	}
+	if failed := desired[types.ResourceIdentifier{Source: SourceEncryptedManifests}]; !failed.Sensitive {
+		t.Errorf("expected the encrypted manifest to be sensitive")
+	}
+}
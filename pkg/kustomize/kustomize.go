@@ -0,0 +1,149 @@
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/vllry/cluster-reconciler/pkg/parse"
+)
+
+// DefaultBinaryPath is the kustomize binary used when none is configured.
+const DefaultBinaryPath = "kustomize"
+
+// DefaultTimeout is the longest a build may run when no timeout is configured.
+const DefaultTimeout = 30 * time.Second
+
+// kustomizationFileNames are the names kustomize reads a kustomization from.
+var kustomizationFileNames = map[string]bool{
+	"kustomization.yaml": true,
+	"kustomization.yml":  true,
+	"Kustomization":      true,
+}
+
+// Renderer builds kustomizations held in memory with the kustomize binary.
+// Only the files of the tree are read, remote bases and resources are rejected.
+type Renderer struct {
+	// BinaryPath is the path of the kustomize binary, it is looked up in PATH if it is not absolute.
+	BinaryPath string
+	// Timeout bounds the duration of a build.
+	Timeout time.Duration
+}
+
+// NewRenderer is to create the renderer struct
+func NewRenderer(binaryPath string) *Renderer {
+	if binaryPath == "" {
+		binaryPath = DefaultBinaryPath
+	}
+	return &Renderer{
+		BinaryPath: binaryPath,
+		Timeout:    DefaultTimeout,
+	}
+}
+
+// Render writes the file tree to a temporary directory, runs kustomize build against path in that tree,
+// and returns the rendered objects.
+// files maps a slash separated path, relative to the root of the tree, to the file content.
+func (r *Renderer) Render(files map[string]string, path string) ([]unstructured.Unstructured, error) {
+	if err := checkLocal(files); err != nil {
+		return nil, err
+	}
+
+	root, err := ioutil.TempDir("", "kustomize")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(root)
+
+	for name, content := range files {
+		target, err := resolve(root, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(target, []byte(content), 0600); err != nil {
+			return nil, err
+		}
+	}
+
+	buildDir, err := resolve(root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.BinaryPath, "build", buildDir)
+	// Without git in PATH, or a proxy to reach, kustomize cannot load remote bases which got past checkLocal.
+	cmd.Env = []string{"HOME=" + root, "PATH=", "GIT_TERMINAL_PROMPT=0", "HTTP_PROXY=http://127.0.0.1:1", "HTTPS_PROXY=http://127.0.0.1:1"}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("kustomize build did not complete within %s", timeout)
+		}
+		return nil, errors.Wrap(err, fmt.Sprintf("kustomize build failed: %s", strings.TrimSpace(stderr.String())))
+	}
+
+	return parse.Parse(&stdout)
+}
+
+// resolve returns the location of name inside root.
+// Absolute names, and names which would escape root, are rejected.
+func resolve(root, name string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid kustomization path %q", name)
+	}
+	return filepath.Join(root, cleaned), nil
+}
+
+// checkLocal rejects kustomizations which refer to remote bases, resources or components.
+func checkLocal(files map[string]string) error {
+	for name, content := range files {
+		if !kustomizationFileNames[path.Base(name)] {
+			continue
+		}
+		kustomization := struct {
+			Resources  []string `json:"resources"`
+			Bases      []string `json:"bases"`
+			Components []string `json:"components"`
+		}{}
+		if err := yaml.Unmarshal([]byte(content), &kustomization); err != nil {
+			// kustomize reports invalid kustomizations.
+			continue
+		}
+		refs := append(append(kustomization.Resources, kustomization.Bases...), kustomization.Components...)
+		for _, ref := range refs {
+			if isRemote(ref) {
+				return fmt.Errorf("remote kustomization reference %q in %s is not allowed", ref, name)
+			}
+		}
+	}
+	return nil
+}
+
+// isRemote returns true if ref is a URL or a repository, such as github.com/org/repo/path?ref=v1.
+func isRemote(ref string) bool {
+	return strings.Contains(ref, "://") || strings.HasPrefix(ref, "git@") || strings.HasPrefix(ref, "github.com/") ||
+		strings.HasPrefix(ref, "gitlab.com/") || strings.HasPrefix(ref, "bitbucket.org/") || strings.Contains(ref, "?ref=")
+}
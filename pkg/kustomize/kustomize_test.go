@@ -0,0 +1,91 @@
+package kustomize
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeKustomize writes a script named name in dir, standing in for the kustomize binary, which runs body
+// with the build directory as $2.
+func fakeKustomize(t *testing.T, dir, name, body string) string {
+	binary := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(binary, []byte("#!/bin/sh\n"+body+"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return binary
+}
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected string
+		invalid  bool
+	}{
+		{name: "", expected: "/root"},
+		{name: "overlays/prod", expected: "/root/overlays/prod"},
+		{name: "overlays/../base", expected: "/root/base"},
+		{name: "..", invalid: true},
+		{name: "../other", invalid: true},
+		{name: "overlays/../../other", invalid: true},
+		{name: "/etc/passwd", invalid: true},
+	}
+	for _, c := range cases {
+		resolved, err := resolve("/root", c.name)
+		if c.invalid {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %s", c.name, resolved)
+			}
+			continue
+		}
+		if err != nil || resolved != c.expected {
+			t.Errorf("%q: expected %s, got %s, %v", c.name, c.expected, resolved, err)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fake-kustomize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The fake build prints the resource of the built directory.
+	renderer := NewRenderer(fakeKustomize(t, dir, "build", `/bin/cat "$2/cm.yaml"`))
+	objects, err := renderer.Render(map[string]string{
+		"overlays/prod/kustomization.yaml": "resources:\n- cm.yaml\n",
+		"overlays/prod/cm.yaml":            "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n",
+	}, "overlays/prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 1 || objects[0].GetName() != "cm" {
+		t.Errorf("unexpected objects %v", objects)
+	}
+
+	if _, err := renderer.Render(map[string]string{"../escape.yaml": ""}, ""); err == nil {
+		t.Errorf("expected an error writing outside of the tree")
+	}
+
+	for _, ref := range []string{"https://example.com/base", "github.com/org/repo/base?ref=v1", "git@github.com:org/repo.git"} {
+		_, err := renderer.Render(map[string]string{"kustomization.yaml": "resources:\n- " + ref + "\n"}, "")
+		if err == nil || !strings.Contains(err.Error(), "remote") {
+			t.Errorf("%s: expected remote reference to be rejected, got %v", ref, err)
+		}
+	}
+
+	failing := NewRenderer(fakeKustomize(t, dir, "fail", `echo "missing base" >&2; exit 1`))
+	if _, err := failing.Render(map[string]string{}, ""); err == nil || !strings.Contains(err.Error(), "missing base") {
+		t.Errorf("expected the build error, got %v", err)
+	}
+
+	hanging := NewRenderer(fakeKustomize(t, dir, "hang", `exec /bin/sleep 10`))
+	hanging.Timeout = 100 * time.Millisecond
+	if _, err := hanging.Render(map[string]string{}, ""); err == nil || !strings.Contains(err.Error(), "did not complete") {
+		t.Errorf("expected the build to time out, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package leaderelection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/vllry/cluster-reconciler/pkg/heartbeat"
+)
+
+// DefaultName is the default name of the lease guarding the agent.
+const DefaultName = "cluster-reconciler-agent-leader"
+
+// ErrLeadershipLost is returned when the lease is lost while running.
+var ErrLeadershipLost = errors.New("leader election lost")
+
+// Config configures the lease guarding the agent.
+type Config struct {
+	// Client is the client of the cluster holding the lease.
+	Client kubernetes.Interface
+	// Namespace and Name locate the lease.
+	Namespace string
+	Name      string
+	// Identity identifies this replica, a unique identity is generated if it is empty.
+	Identity string
+
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// Run waits to acquire the lease, and calls run while holding it.
+// run is stopped when the lease is lost, or stopCh is closed. The lease is released once run returns,
+// so another replica can take over without waiting for the lease to expire.
+// ErrLeadershipLost is returned if the lease is lost, otherwise the error of run is returned.
+func Run(log logr.Logger, config Config, stopCh <-chan struct{}, run func(stopCh <-chan struct{}) error) error {
+	if config.Name == heartbeat.LeaseName {
+		// The heartbeat would overwrite the holder and the renew time of the lease.
+		return fmt.Errorf("the lease %s is renewed as the heartbeat of the agent, use another name", config.Name)
+	}
+	identity := config.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		identity = hostname + "_" + string(uuid.NewUUID())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runStopCh := make(chan struct{})
+	var stopOnce sync.Once
+	stopRun := func() {
+		stopOnce.Do(func() { close(runStopCh) })
+	}
+	started := make(chan struct{})
+	runDone := make(chan struct{})
+	var runErr error
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Namespace: config.Namespace,
+				Name:      config.Name,
+			},
+			Client:     config.Client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration:   config.LeaseDuration,
+		RenewDeadline:   config.RenewDeadline,
+		RetryPeriod:     config.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            config.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				log.Info("acquired lease", "identity", identity)
+				close(started)
+				go func() {
+					<-leaderCtx.Done()
+					stopRun()
+				}()
+				runErr = run(runStopCh)
+				close(runDone)
+				// Release the lease only once run has stopped.
+				cancel()
+			},
+			OnStoppedLeading: func() {
+				log.Info("stopped leading", "identity", identity)
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		select {
+		case <-stopCh:
+		case <-ctx.Done():
+			return
+		}
+		stopRun()
+		select {
+		case <-started:
+			<-runDone
+		default:
+		}
+		cancel()
+	}()
+
+	elector.Run(ctx)
+
+	lost := ctx.Err() == nil
+	stopRun()
+	select {
+	case <-started:
+		<-runDone
+	default:
+	}
+	if lost {
+		return ErrLeadershipLost
+	}
+	return runErr
+}
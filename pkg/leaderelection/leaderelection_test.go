@@ -0,0 +1,128 @@
+package leaderelection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/vllry/cluster-reconciler/pkg/heartbeat"
+)
+
+func TestRunFailover(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	config := func(identity string) Config {
+		return Config{
+			Client:        client,
+			Namespace:     "default",
+			Name:          "agent",
+			Identity:      identity,
+			LeaseDuration: time.Minute,
+			RenewDeadline: 30 * time.Second,
+			RetryPeriod:   50 * time.Millisecond,
+		}
+	}
+
+	leading := make(chan string, 2)
+	run := func(identity string) func(stopCh <-chan struct{}) error {
+		return func(stopCh <-chan struct{}) error {
+			leading <- identity
+			<-stopCh
+			return nil
+		}
+	}
+
+	firstStopCh := make(chan struct{})
+	firstDone := make(chan error)
+	go func() {
+		firstDone <- Run(logf.Log, config("first"), firstStopCh, run("first"))
+	}()
+	select {
+	case identity := <-leading:
+		if identity != "first" {
+			t.Fatalf("expected first to lead, got %s", identity)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("first never acquired the lease")
+	}
+
+	secondStopCh := make(chan struct{})
+	defer close(secondStopCh)
+	go func() {
+		_ = Run(logf.Log, config("second"), secondStopCh, run("second"))
+	}()
+	select {
+	case identity := <-leading:
+		t.Fatalf("%s led while first held the lease", identity)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// The lease is released on stop, so second takes over well before the lease duration.
+	close(firstStopCh)
+	if err := <-firstDone; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case identity := <-leading:
+		if identity != "second" {
+			t.Fatalf("expected second to lead, got %s", identity)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second did not take over the released lease")
+	}
+}
+
+func TestRunWithHeartbeat(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	config := Config{
+		Client:        client,
+		Namespace:     "cluster1",
+		Name:          DefaultName,
+		Identity:      "replica",
+		LeaseDuration: time.Minute,
+		RenewDeadline: 30 * time.Second,
+		RetryPeriod:   50 * time.Millisecond,
+	}
+	renewer := &heartbeat.Renewer{
+		Client:        client,
+		Log:           logf.Log,
+		Namespace:     "cluster1",
+		Holder:        "agent",
+		LeaseDuration: time.Minute,
+		RenewInterval: time.Second,
+	}
+
+	// The heartbeat is renewed by the leader, in the namespace of the election lease.
+	err := Run(logf.Log, config, make(chan struct{}), func(stopCh <-chan struct{}) error {
+		if err := renewer.Renew(); err != nil {
+			return err
+		}
+		leases := client.CoordinationV1().Leases("cluster1")
+		election, err := leases.Get(context.Background(), DefaultName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if holder := election.Spec.HolderIdentity; holder == nil || *holder != "replica" {
+			t.Errorf("expected the election lease to be held by the replica, got %v", holder)
+		}
+		beat, err := leases.Get(context.Background(), heartbeat.LeaseName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if holder := beat.Spec.HolderIdentity; holder == nil || *holder != "agent" {
+			t.Errorf("expected the heartbeat lease to be held by the agent, got %v", holder)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config.Name = heartbeat.LeaseName
+	if err := Run(logf.Log, config, make(chan struct{}), func(stopCh <-chan struct{}) error { return nil }); err == nil {
+		t.Errorf("expected the heartbeat lease to be refused for leader election")
+	}
+}
@@ -1,43 +1,350 @@
 package parse
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/serializer/json"
-	"k8s.io/apimachinery/pkg/runtime/serializer/streaming"
-	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
 )
 
+// Error is an error parsing a document, with where it was found.
+type Error struct {
+	// Document is the index of the document in the stream. Blank documents are not counted, while documents
+	// holding only comments are, like the other documents which decode to no object.
+	Document int
+	// Line is the line of the error if it is known, or the first line of the document.
+	Line int
+	// Path is the field path of the error, such as items[1].metadata.name. It is empty if it is not known.
+	Path string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Location(), e.Err)
+}
+
+// Location describes where the error was found, without the cause which may quote the document.
+func (e *Error) Location() string {
+	location := fmt.Sprintf("document %d at line %d", e.Document, e.Line)
+	if e.Path != "" {
+		location = fmt.Sprintf("%s, field %s", location, e.Path)
+	}
+	return location
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// fieldError is an error at a field path of a document.
+type fieldError struct {
+	path string
+	err  error
+}
+
+func (e *fieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.path, e.err)
+}
+
+// yamlLinePattern matches the line of YAML errors, which is relative to the document.
+var yamlLinePattern = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// Decoder reads objects from a stream of YAML or JSON documents, one document at a time.
+// YAML documents are separated by ---, and a JSON document may hold several objects, or an array of objects.
+// List kinds are flattened into their items, and empty or comment only documents are skipped.
+// Each document is read whole before its objects are returned, so a large List or JSON array is held in memory.
+type Decoder struct {
+	reader *bufio.Reader
+	// line is the number of lines read.
+	line int
+	// documents is the number of documents read.
+	documents int
+	// pending holds the objects of the current document which were not returned yet.
+	pending []unstructured.Unstructured
+}
+
+// NewDecoder is to create the decoder struct
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		reader: bufio.NewReader(r),
+	}
+}
+
+// Decode returns the next object, or io.EOF once every document was read.
+// A document which cannot be parsed returns an *Error, and decoding continues with the next document.
+func (d *Decoder) Decode() (unstructured.Unstructured, error) {
+	for len(d.pending) == 0 {
+		document, start, err := d.readDocument()
+		if err != nil {
+			return unstructured.Unstructured{}, err
+		}
+		index := d.documents
+		d.documents++
+		d.pending, err = decodeDocument(document)
+		if err != nil {
+			return unstructured.Unstructured{}, locate(err, index, start)
+		}
+	}
+	obj := d.pending[0]
+	d.pending = d.pending[1:]
+	return obj, nil
+}
+
+// readDocument returns the next document which is not blank, and the line of its first line which is not blank.
+func (d *Decoder) readDocument() ([]byte, int, error) {
+	var buffer bytes.Buffer
+	start := 0
+	for {
+		line, err := d.reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		if len(line) > 0 {
+			d.line++
+		}
+
+		trimmed := bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, []byte("---")) && len(bytes.TrimSpace(line[3:])) == 0 {
+			if start > 0 {
+				return buffer.Bytes(), start, nil
+			}
+			buffer.Reset()
+		} else {
+			if start == 0 && len(trimmed) > 0 {
+				start = d.line
+			}
+			if start > 0 {
+				buffer.Write(line)
+			}
+		}
+
+		if err == io.EOF {
+			if start > 0 {
+				return buffer.Bytes(), start, nil
+			}
+			return nil, 0, io.EOF
+		}
+	}
+}
+
+// locate wraps an error of the document at index, which starts at line start, with where it was found.
+func locate(err error, index, start int) *Error {
+	located := &Error{Document: index, Line: start, Err: err}
+
+	var fieldErr *fieldError
+	if errors.As(err, &fieldErr) {
+		located.Path = fieldErr.path
+		located.Err = fieldErr.err
+	}
+
+	if match := yamlLinePattern.FindStringSubmatch(located.Err.Error()); match != nil {
+		// Lines of YAML errors are relative to the document, they are replaced by the line in the stream.
+		if line, convErr := strconv.Atoi(match[1]); convErr == nil {
+			located.Line = start + line - 1
+			located.Err = errors.New(strings.TrimPrefix(located.Err.Error(), match[0]))
+		}
+	}
+	return located
+}
+
+// Parse reads every object of r, it returns the first error.
+func Parse(r io.Reader) ([]unstructured.Unstructured, error) {
+	decoder := NewDecoder(r)
+	objects := []unstructured.Unstructured{}
+	for {
+		obj, err := decoder.Decode()
+		if err == io.EOF {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+}
+
 func ParseYaml(validYaml string) ([]unstructured.Unstructured, error) {
-	yamlDecoder := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+	return Parse(strings.NewReader(validYaml))
+}
+
+// decodeDocument returns the objects of a document.
+func decodeDocument(document []byte) ([]unstructured.Unstructured, error) {
+	trimmed := bytes.TrimSpace(document)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		// YAML flow collections also start with a bracket, they are decoded as YAML, which also locates
+		// JSON syntax errors.
+		objects, err := decodeJSON(trimmed)
+		var syntaxErr *json.SyntaxError
+		if err == nil || !errors.As(err, &syntaxErr) {
+			return objects, err
+		}
+	}
+
+	data, err := yaml.YAMLToJSON(document)
+	if err != nil {
+		return nil, err
+	}
+	return decodeValue(data, "")
+}
 
-	// Parse the objects from the yaml.
-	var objects []unstructured.Unstructured
-	reader := json.YAMLFramer.NewFrameReader(ioutil.NopCloser(bytes.NewReader([]byte(validYaml))))
-	d := streaming.NewDecoder(reader, yamlDecoder)
+// decodeJSON returns the objects of a document holding a sequence of JSON values.
+func decodeJSON(document []byte) ([]unstructured.Unstructured, error) {
+	objects := []unstructured.Unstructured{}
+	decoder := json.NewDecoder(bytes.NewReader(document))
 	for {
-		obj, _, err := d.Decode(nil, nil)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err == io.EOF {
+			return objects, nil
+		} else if err != nil {
+			return nil, err
+		}
+		objs, err := decodeValue(value, "")
 		if err != nil {
-			if err == io.EOF {
-				break
+			return nil, err
+		}
+		objects = append(objects, objs...)
+	}
+}
+
+// decodeValue returns the objects of a JSON value at path, which is an object, an array of objects, or null.
+func decodeValue(data []byte, path string) ([]unstructured.Unstructured, error) {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")):
+		return nil, nil
+	case trimmed[0] == '[':
+		var values []json.RawMessage
+		if err := json.Unmarshal(trimmed, &values); err != nil {
+			return nil, err
+		}
+		objects := []unstructured.Unstructured{}
+		for i, value := range values {
+			objs, err := decodeValue(value, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
 			}
-			return []unstructured.Unstructured{}, err
+			objects = append(objects, objs...)
 		}
+		return objects, nil
+	case trimmed[0] != '{':
+		return nil, newFieldError(path, "", fmt.Errorf("expected an object, got %s", describeValue(trimmed)))
+	}
 
-		// Convert the Object to Unstructured.
-		switch t := obj.(type) {
-		case *unstructured.Unstructured:
-			objects = append(objects, *t)
-		default:
-			return nil, errors.New(fmt.Sprintf("failed to convert object, unexpected type %s", reflect.TypeOf(obj)))
+	obj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, trimmed)
+	switch {
+	case runtime.IsMissingKind(err):
+		return nil, newFieldError(path, "kind", errors.New("kind is missing"))
+	case runtime.IsMissingVersion(err):
+		return nil, newFieldError(path, "apiVersion", errors.New("apiVersion is missing"))
+	case err != nil:
+		return nil, newFieldError(path, "", err)
+	}
+	switch t := obj.(type) {
+	case *unstructured.Unstructured:
+		if field, err := validateMetadata(t.Object); err != nil {
+			return nil, newFieldError(path, field, err)
 		}
+		return []unstructured.Unstructured{*t}, nil
+	case *unstructured.UnstructuredList:
+		return flattenList(t, path)
+	default:
+		return nil, newFieldError(path, "", fmt.Errorf("failed to convert object, unexpected type %T", obj))
 	}
+}
 
+// flattenList returns the items of a list at path, and the items of the lists it holds.
+func flattenList(list *unstructured.UnstructuredList, path string) ([]unstructured.Unstructured, error) {
+	objects := []unstructured.Unstructured{}
+	for i, item := range list.Items {
+		itemPath := joinPath(path, fmt.Sprintf("items[%d]", i))
+		if item.IsList() {
+			if nested, err := item.ToList(); err == nil {
+				objs, err := flattenList(nested, itemPath)
+				if err != nil {
+					return nil, err
+				}
+				objects = append(objects, objs...)
+				continue
+			}
+		}
+		if item.GetKind() == "" {
+			return nil, newFieldError(itemPath, "kind", errors.New("kind is missing"))
+		}
+		if field, err := validateMetadata(item.Object); err != nil {
+			return nil, newFieldError(itemPath, field, err)
+		}
+		objects = append(objects, item)
+	}
 	return objects, nil
 }
+
+// validateMetadata checks the types of the metadata fields which are read from objects, since accessors ignore
+// fields of the wrong type. It returns the path of the invalid field.
+func validateMetadata(obj map[string]interface{}) (string, error) {
+	value, found := obj["metadata"]
+	if !found {
+		return "", nil
+	}
+	metadata, ok := value.(map[string]interface{})
+	if !ok {
+		return "metadata", errors.New("expected an object")
+	}
+	for _, field := range []string{"name", "namespace", "generateName"} {
+		if value, found := metadata[field]; found {
+			if _, ok := value.(string); !ok {
+				return "metadata." + field, errors.New("expected a string")
+			}
+		}
+	}
+	for _, field := range []string{"labels", "annotations"} {
+		value, found := metadata[field]
+		if !found || value == nil {
+			continue
+		}
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return "metadata." + field, errors.New("expected an object")
+		}
+		for key, value := range values {
+			if _, ok := value.(string); !ok {
+				return fmt.Sprintf("metadata.%s.%s", field, key), errors.New("expected a string")
+			}
+		}
+	}
+	return "", nil
+}
+
+func newFieldError(path, field string, err error) error {
+	if path == "" && field == "" {
+		return err
+	}
+	return &fieldError{path: joinPath(path, field), err: err}
+}
+
+func joinPath(path, field string) string {
+	if path == "" || field == "" || strings.HasPrefix(field, "[") {
+		return path + field
+	}
+	return path + "." + field
+}
+
+// describeValue names the type of a JSON value in errors.
+func describeValue(data []byte) string {
+	switch data[0] {
+	case '"':
+		return "a string"
+	case 't', 'f':
+		return "a boolean"
+	default:
+		return "a number"
+	}
+}
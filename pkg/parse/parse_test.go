@@ -0,0 +1,144 @@
+package parse
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := `# leading comment only
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+---
+# comment only
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: b
+- apiVersion: v1
+  kind: ConfigMapList
+  items:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: c
+---
+[{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "d"}}, {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "e"}}]
+---
+{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "f"}}
+{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "g"}}
+---
+{apiVersion: v1, kind: ConfigMap, metadata: {name: h}}
+`
+	objects, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, obj := range objects {
+		if obj.GetKind() != "ConfigMap" {
+			t.Errorf("unexpected kind %q of %q", obj.GetKind(), obj.GetName())
+		}
+		names = append(names, obj.GetName())
+	}
+	if expected := []string{"a", "b", "c", "d", "e", "f", "g", "h"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	for _, invalid := range []string{"kind: [", "just a string", "metadata:\n  name: no-kind"} {
+		if _, err := Parse(strings.NewReader(invalid)); err == nil {
+			t.Errorf("expected an error parsing %q", invalid)
+		}
+	}
+}
+
+func TestDecoderStreams(t *testing.T) {
+	reader, writer := io.Pipe()
+	decoder := NewDecoder(reader)
+	go writer.Write([]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\n"))
+
+	// The first object is returned before the stream ends.
+	obj, err := decoder.Decode()
+	if err != nil || obj.GetName() != "a" {
+		t.Fatalf("expected object a, got %q with err %v", obj.GetName(), err)
+	}
+	writer.Close()
+	if _, err := decoder.Decode(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Error
+	}{
+		{
+			name: "yaml syntax",
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+ namespace: default
+`,
+			expected: Error{Document: 1, Line: 10},
+		},
+		{
+			name:     "missing kind",
+			input:    "# comment\n---\n\napiVersion: v1\nmetadata:\n  name: a\n",
+			expected: Error{Document: 1, Line: 4, Path: "kind"},
+		},
+		{
+			name:     "after comment only document",
+			input:    "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\n---\n# comment\n---\nkind: [\n",
+			expected: Error{Document: 2, Line: 9},
+		},
+		{
+			name:     "list item",
+			input:    "apiVersion: v1\nkind: List\nitems:\n- apiVersion: v1\n  kind: ConfigMap\n  metadata:\n    name: [a]\n",
+			expected: Error{Document: 0, Line: 1, Path: "items[0].metadata.name"},
+		},
+		{
+			name:     "json array",
+			input:    `[{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a"}}, {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"labels": {"a": 1}}}]`,
+			expected: Error{Document: 0, Line: 1, Path: "[1].metadata.labels.a"},
+		},
+	}
+	for _, c := range cases {
+		_, err := Parse(strings.NewReader(c.input))
+		parseErr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("%s: expected a parse error, got %v", c.name, err)
+			continue
+		}
+		if parseErr.Document != c.expected.Document || parseErr.Line != c.expected.Line || parseErr.Path != c.expected.Path {
+			t.Errorf("%s: expected document %d, line %d and path %q, got %v", c.name, c.expected.Document, c.expected.Line, c.expected.Path, parseErr)
+		}
+	}
+
+	// Decoding continues after a document which cannot be parsed.
+	decoder := NewDecoder(strings.NewReader("kind: [\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n"))
+	if _, err := decoder.Decode(); err == nil {
+		t.Fatalf("expected an error for the first document")
+	}
+	if obj, err := decoder.Decode(); err != nil || obj.GetName() != "b" {
+		t.Errorf("expected object b, got %q with err %v", obj.GetName(), err)
+	}
+}
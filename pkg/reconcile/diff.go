@@ -0,0 +1,185 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// MaxDiffEntries bounds the number of changes in a diff summary.
+const MaxDiffEntries = 10
+
+// maxDiffValueLength bounds the length of each value in a diff summary.
+const maxDiffValueLength = 40
+
+// FieldChange is a single changed field between the desired and the actual state of an object.
+type FieldChange struct {
+	// Path is the field path, such as spec.template.spec.containers[0].image.
+	Path string
+	// Actual is the value in the cluster, it is nil if the field is added.
+	Actual interface{}
+	// Desired is the desired value, it is nil if the field is removed.
+	Desired interface{}
+}
+
+// Diff returns the fields which differ between the desired and the actual state of an object, sorted by path.
+// Status and system metadata are ignored, only the labels and annotations set in the desired state are compared.
+func Diff(desired unstructured.Unstructured, actual unstructured.Unstructured) []FieldChange {
+	changes := []FieldChange{}
+	for key, desiredVal := range desired.Object {
+		if key == "status" {
+			continue
+		}
+		if key == "metadata" {
+			for _, field := range []string{"labels", "annotations"} {
+				desiredFields, _, _ := unstructured.NestedStringMap(desired.Object, "metadata", field)
+				actualFields, _, _ := unstructured.NestedStringMap(actual.Object, "metadata", field)
+				for name, value := range desiredFields {
+					if actualValue, found := actualFields[name]; !found {
+						changes = append(changes, FieldChange{Path: fmt.Sprintf("metadata.%s.%s", field, name), Desired: value})
+					} else if actualValue != value {
+						changes = append(changes, FieldChange{Path: fmt.Sprintf("metadata.%s.%s", field, name), Actual: actualValue, Desired: value})
+					}
+				}
+			}
+			continue
+		}
+		actualVal, found := actual.Object[key]
+		if !found {
+			changes = append(changes, FieldChange{Path: key, Desired: desiredVal})
+			continue
+		}
+		changes = append(changes, diffValues(key, desiredVal, actualVal)...)
+	}
+	for key, actualVal := range actual.Object {
+		if key == "metadata" || key == "status" {
+			continue
+		}
+		if _, found := desired.Object[key]; !found {
+			changes = append(changes, FieldChange{Path: key, Actual: actualVal})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffValues(path string, desired, actual interface{}) []FieldChange {
+	switch desiredTyped := desired.(type) {
+	case map[string]interface{}:
+		actualTyped, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+		changes := []FieldChange{}
+		for key, desiredVal := range desiredTyped {
+			actualVal, found := actualTyped[key]
+			if !found {
+				changes = append(changes, FieldChange{Path: path + "." + key, Desired: desiredVal})
+				continue
+			}
+			changes = append(changes, diffValues(path+"."+key, desiredVal, actualVal)...)
+		}
+		for key, actualVal := range actualTyped {
+			if _, found := desiredTyped[key]; !found {
+				changes = append(changes, FieldChange{Path: path + "." + key, Actual: actualVal})
+			}
+		}
+		return changes
+	case []interface{}:
+		actualTyped, ok := actual.([]interface{})
+		if !ok || len(actualTyped) != len(desiredTyped) {
+			break
+		}
+		changes := []FieldChange{}
+		for i := range desiredTyped {
+			changes = append(changes, diffValues(fmt.Sprintf("%s[%d]", path, i), desiredTyped[i], actualTyped[i])...)
+		}
+		return changes
+	}
+
+	if equality.Semantic.DeepEqual(desired, actual) {
+		return nil
+	}
+	return []FieldChange{{Path: path, Actual: actual, Desired: desired}}
+}
+
+// SummarizeDiff returns a bounded, human readable summary of the changes of an object.
+// Values are redacted for Secrets and sensitive objects, and truncated otherwise.
+func SummarizeDiff(changes []FieldChange, kind string, sensitive bool) []string {
+	redact := sensitive || kind == "Secret"
+	summary := []string{}
+	for i, change := range changes {
+		if i == MaxDiffEntries {
+			summary = append(summary, fmt.Sprintf("... and %d more", len(changes)-MaxDiffEntries))
+			break
+		}
+		if redact {
+			summary = append(summary, change.Path+": changed")
+			continue
+		}
+		summary = append(summary, fmt.Sprintf("%s: %s -> %s", change.Path, summarizeValue(change.Actual), summarizeValue(change.Desired)))
+	}
+	return summary
+}
+
+func summarizeValue(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "<unprintable>"
+	}
+	s := string(data)
+	if len(s) > maxDiffValueLength {
+		// The value is cut on a rune boundary, so the summary stays valid UTF-8.
+		end := maxDiffValueLength
+		for end > 0 && !utf8.RuneStart(s[end]) {
+			end--
+		}
+		s = s[:end] + "..."
+	}
+	return strings.TrimSpace(s)
+}
+
+// IntentOf returns the fields of obj which are compared with the desired state, so they can be shown side by side.
+// Status and system metadata are dropped, and only the labels and annotations set in the desired state are kept.
+func IntentOf(obj unstructured.Unstructured, desired unstructured.Unstructured) unstructured.Unstructured {
+	intent := unstructured.Unstructured{Object: map[string]interface{}{}}
+	for key, val := range obj.Object {
+		if key == "metadata" || key == "status" {
+			continue
+		}
+		intent.Object[key] = val
+	}
+
+	metadata := map[string]interface{}{}
+	for _, field := range []string{"name", "namespace"} {
+		if value, found, _ := unstructured.NestedString(obj.Object, "metadata", field); found {
+			metadata[field] = value
+		}
+	}
+	for _, field := range []string{"labels", "annotations"} {
+		desiredFields, _, _ := unstructured.NestedStringMap(desired.Object, "metadata", field)
+		fields, _, _ := unstructured.NestedStringMap(obj.Object, "metadata", field)
+		kept := map[string]interface{}{}
+		for name := range desiredFields {
+			if value, found := fields[name]; found {
+				kept[name] = value
+			}
+		}
+		if len(kept) > 0 {
+			metadata[field] = kept
+		}
+	}
+	intent.Object["metadata"] = metadata
+	return intent
+}
@@ -0,0 +1,61 @@
+package reconcile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDiff(t *testing.T) {
+	desired := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":   "cm",
+			"labels": map[string]interface{}{"app": "demo"},
+		},
+		"data": map[string]interface{}{"color": "purple", "size": "large"},
+	}}
+	actual := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":            "cm",
+			"resourceVersion": "42",
+			"labels":          map[string]interface{}{"app": "demo", "extra": "label"},
+		},
+		"data":   map[string]interface{}{"color": "pink", "shape": "round"},
+		"status": map[string]interface{}{"ignored": true},
+	}}
+
+	summary := SummarizeDiff(Diff(desired, actual), "ConfigMap", false)
+	expected := []string{
+		`data.color: "pink" -> "purple"`,
+		`data.shape: "round" -> <none>`,
+		`data.size: <none> -> "large"`,
+	}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("expected %v, got %v", expected, summary)
+	}
+
+	summary = SummarizeDiff(Diff(desired, actual), "Secret", false)
+	expected = []string{"data.color: changed", "data.shape: changed", "data.size: changed"}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("expected %v, got %v", expected, summary)
+	}
+}
+
+func TestSummarizeDiffTruncatesRunes(t *testing.T) {
+	// Once quoted, the cut at 40 bytes falls in the middle of the last rune.
+	desired := strings.Repeat("a", 38) + "é"
+	summary := SummarizeDiff([]FieldChange{{Path: "data.key", Actual: "a", Desired: desired}}, "ConfigMap", false)
+	if len(summary) != 1 || !utf8.ValidString(summary[0]) {
+		t.Fatalf("expected a valid UTF-8 summary, got %q", summary)
+	}
+	if expected := `data.key: "a" -> "` + strings.Repeat("a", 38) + "..."; summary[0] != expected {
+		t.Errorf("expected %q, got %q", expected, summary[0])
+	}
+}
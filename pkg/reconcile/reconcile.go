@@ -9,7 +9,9 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 
@@ -17,19 +19,34 @@ import (
 	"github.com/vllry/cluster-reconciler/pkg/types"
 )
 
-const reconcilerAnnotationKey = "cluster-reconciler-managed"
-const reconcilerAnnotationValue = "true"
-
 type FetchDesiredObjectFunc func() (map[types.ResourceIdentifier]types.Semistructured, error)
 
+// ReconcileOptions changes how ReconcileCluster writes to the cluster.
+type ReconcileOptions struct {
+	// DryRun sends every create and update as a server-side dry run, so nothing in the cluster changes.
+	DryRun bool
+	// ReadOnly fetches and compares the state of every object, but never creates or updates objects.
+	// Objects which would be written are reported as drifted.
+	ReadOnly bool
+}
+
 // ReconcileResult is to track the result of result apply
 type ReconcileResult struct {
 	Identifier types.ResourceIdentifier
 	Err        error
 	Updated    bool
+	// Drifted is set in read only mode for objects which are missing or differ from the desired state.
+	Drifted bool
+	// Diff summarizes the fields which were updated, or which drifted in read only mode.
+	Diff []string
+	// Pruned is set for objects which were deleted, or would be deleted in read only mode, since they
+	// were removed from the inventory.
+	Pruned bool
+	// Exists is set for desired objects which exist in the cluster once reconciled.
+	Exists bool
 }
 
-func ReconcileCluster(client kubernetes.Interface, dynamicClient dynamic.Interface, fetchFunc FetchDesiredObjectFunc) ([]ReconcileResult, error) {
+func ReconcileCluster(client kubernetes.Interface, dynamicClient dynamic.Interface, fetchFunc FetchDesiredObjectFunc, opts ReconcileOptions) ([]ReconcileResult, error) {
 	results := []ReconcileResult{}
 
 	//  Fetch resources from the cluster inventory (desired actualState).
@@ -56,20 +73,39 @@ func ReconcileCluster(client kubernetes.Interface, dynamicClient dynamic.Interfa
 	// Create/update desired resources.
 	for obj, desiredState := range desiredObjects {
 		result := ReconcileResult{}
-		if invalidGVR(desiredState.Identifier.GroupVersionResource) {
+		if desiredState.Err != nil {
+			result.Identifier = desiredState.Identifier
+			result.Err = desiredState.Err
+		} else if invalidGVR(desiredState.Identifier.GroupVersionResource) {
 			result.Identifier = desiredState.Identifier
 			result.Err = fmt.Errorf("Invalid gvr")
 		} else if actualState, found := currentResources[obj]; found {
+			result.Exists = true
 			if !sameIntent(desiredState.Unstructured, actualState.Unstructured) {
-				// Update resource.
-				result.Identifier, result.Err = updateResource(dynamicClient, desiredState)
-				result.Updated = true
+				changes := Diff(desiredState.Unstructured, actualState.Unstructured)
+				result.Diff = SummarizeDiff(changes, desiredState.Identifier.GroupVersionKind.Kind, desiredState.Sensitive)
+				if opts.ReadOnly {
+					result.Identifier = desiredState.Identifier
+					result.Drifted = true
+				} else {
+					// Update resource.
+					result.Identifier, result.Err = updateResource(dynamicClient, desiredState, opts.DryRun)
+					result.Updated = true
+				}
 			} else {
 				result.Identifier = actualState.Identifier
 			}
+		} else if opts.ReadOnly {
+			result.Identifier = desiredState.Identifier
+			result.Drifted = true
+			result.Diff = []string{"object is missing"}
 		} else {
-			result.Identifier, result.Err = createResource(dynamicClient, desiredState)
+			result.Identifier, result.Err = createResource(dynamicClient, desiredState, opts.DryRun)
 			result.Updated = true
+			result.Exists = result.Err == nil && !opts.DryRun
+		}
+		if desiredState.Err == nil && desiredState.Sensitive && result.Err != nil {
+			result.Err = redactError(result.Err)
 		}
 		results = append(results, result)
 	}
@@ -77,15 +113,81 @@ func ReconcileCluster(client kubernetes.Interface, dynamicClient dynamic.Interfa
 	return results, nil
 }
 
-// deleteOldManagedResources deletes all managed resources that are not in the provided desired state.
-func deleteOldManagedResources(typedClient kubernetes.Interface, dynamicClient dynamic.Interface, desiredObjects map[types.ResourceIdentifier]types.Semistructured) error {
+// ObjectDiff is the difference between the desired and the live state of an object.
+type ObjectDiff struct {
+	Identifier types.ResourceIdentifier
+	Err        error
+	// Drifted is set for objects which are missing, or which ReconcileCluster would update.
+	Drifted bool
+	// Desired is the desired state of the object.
+	Desired unstructured.Unstructured
+	// Actual is the live state of the object, it is nil if the object is missing.
+	Actual *unstructured.Unstructured
+	// Sensitive marks objects whose content must not be surfaced.
+	Sensitive bool
+}
+
+// DiffCluster compares the desired objects with their live state, without writing to the cluster.
+// Objects are compared the way ReconcileCluster compares them, so an object drifts if and only if it would be written.
+func DiffCluster(dynamicClient dynamic.Interface, fetchFunc FetchDesiredObjectFunc) ([]ObjectDiff, error) {
+	diffs := []ObjectDiff{}
+
+	desiredObjects, err := fetchFunc()
+	if err != nil {
+		return diffs, err
+	}
+	desiredResourceList := make([]types.ResourceIdentifier, 0, len(desiredObjects))
+	for id := range desiredObjects {
+		desiredResourceList = append(desiredResourceList, id)
+	}
+	currentResources, err := fetchResourceState(dynamicClient, desiredResourceList)
+	if err != nil {
+		return diffs, err
+	}
+
+	for id, desiredState := range desiredObjects {
+		diff := ObjectDiff{
+			Identifier: desiredState.Identifier,
+			Desired:    desiredState.Unstructured,
+			Sensitive:  desiredState.Sensitive,
+		}
+		if desiredState.Err != nil {
+			diff.Err = desiredState.Err
+		} else if invalidGVR(desiredState.Identifier.GroupVersionResource) {
+			diff.Err = fmt.Errorf("Invalid gvr")
+		} else if actualState, found := currentResources[id]; found {
+			diff.Actual = &actualState.Unstructured
+			diff.Drifted = !sameIntent(desiredState.Unstructured, actualState.Unstructured)
+		} else {
+			diff.Drifted = true
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+// PruneManagedResources deletes the managed objects of an inventory that are not in the provided desired state.
+// Objects are matched by kind, namespace and name, so objects served by several API groups are not pruned.
+// In read only mode, objects which would be deleted are reported as drifted.
+func PruneManagedResources(typedClient kubernetes.Interface, dynamicClient dynamic.Interface, desiredObjects map[types.ResourceIdentifier]types.Semistructured, inventory string, opts ReconcileOptions) ([]ReconcileResult, error) {
+	results := []ReconcileResult{}
+
+	desired := map[pruneKey]bool{}
+	for _, obj := range desiredObjects {
+		desired[pruneKey{
+			Kind:           obj.Identifier.GroupVersionKind.Kind,
+			NamespacedName: obj.Identifier.NamespacedName,
+		}] = true
+	}
+
 	// Fetch all applicable APIs in tthe cluster.
 	clusterResourceTypes, err := discover.FetchApiVersions(typedClient)
 	if err != nil {
-		return err
+		return results, err
 	}
 
 	// TODO FetchApiVersions's signature feels wonky to consume.
+	seen := map[k8stypes.UID]bool{}
 	for groupName, resources := range clusterResourceTypes {
 		for resource, versions := range resources {
 			version := versions[0] // Use an arbitrary supported API version.
@@ -94,49 +196,94 @@ func deleteOldManagedResources(typedClient kubernetes.Interface, dynamicClient d
 				Version:  version,
 				Resource: resource,
 			}
-			items, err := dynamicClient.Resource(gvr).List(context.Background(), metav1.ListOptions{})
+			items, err := dynamicClient.Resource(gvr).List(context.Background(), metav1.ListOptions{
+				LabelSelector: labels.SelectorFromSet(labels.Set{types.InventoryLabelKey: inventory}).String(),
+			})
 			if err != nil {
-				return errors.Wrap(err, fmt.Sprintf("failed to list %s resources in %s/%s api", resource, groupName, version))
+				return results, errors.Wrap(err, fmt.Sprintf("failed to list %s resources in %s/%s api", resource, groupName, version))
 			}
 
 			for _, obj := range items.Items {
-				if isReconcilerManaged(obj) {
-					/*
-						_, found := desiredObjects[gvk]; !found {
-							fmt.Printf("Deleting managed object: %v\n", obj.GetName())
-							// TODO delete
-						}
-					*/
+				if !isReconcilerManaged(obj) || seen[obj.GetUID()] {
+					continue
+				}
+				seen[obj.GetUID()] = true
+				namespacedName := k8stypes.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+				if desired[pruneKey{Kind: obj.GetKind(), NamespacedName: namespacedName}] {
+					continue
 				}
+
+				result := ReconcileResult{
+					Identifier: types.ResourceIdentifier{
+						GroupVersionKind:     obj.GroupVersionKind(),
+						GroupVersionResource: gvr,
+						NamespacedName:       namespacedName,
+					},
+					Pruned: true,
+					Diff:   []string{"object is not in the inventory"},
+				}
+				if opts.ReadOnly {
+					result.Drifted = true
+				} else {
+					result.Err = deleteResource(dynamicClient, gvr, obj, opts.DryRun)
+				}
+				results = append(results, result)
 			}
 		}
 	}
 
-	return nil
+	return results, nil
+}
+
+// pruneKey identifies an object regardless of the API group and version it is served by.
+type pruneKey struct {
+	Kind           string
+	NamespacedName k8stypes.NamespacedName
 }
 
-func updateResource(client dynamic.Interface, resource types.Semistructured) (types.ResourceIdentifier, error) {
+func updateResource(client dynamic.Interface, resource types.Semistructured, dryRun bool) (types.ResourceIdentifier, error) {
 	_, err := client.Resource(resource.Identifier.GroupVersionResource).Namespace(resource.Identifier.NamespacedName.Namespace).Update(
 		context.Background(),
 		&resource.Unstructured,
-		metav1.UpdateOptions{},
+		metav1.UpdateOptions{DryRun: dryRunOption(dryRun)},
 	)
 	return resource.Identifier, err
 }
 
-func createResource(client dynamic.Interface, resource types.Semistructured) (types.ResourceIdentifier, error) {
+func createResource(client dynamic.Interface, resource types.Semistructured, dryRun bool) (types.ResourceIdentifier, error) {
 	_, err := client.Resource(resource.Identifier.GroupVersionResource).Namespace(resource.Identifier.NamespacedName.Namespace).Create(
 		context.Background(),
 		&resource.Unstructured,
-		metav1.CreateOptions{},
+		metav1.CreateOptions{DryRun: dryRunOption(dryRun)},
 	)
 	return resource.Identifier, err
 }
 
+func deleteResource(client dynamic.Interface, gvr schema.GroupVersionResource, obj unstructured.Unstructured, dryRun bool) error {
+	uid := obj.GetUID()
+	err := client.Resource(gvr).Namespace(obj.GetNamespace()).Delete(
+		context.Background(),
+		obj.GetName(),
+		metav1.DeleteOptions{DryRun: dryRunOption(dryRun), Preconditions: &metav1.Preconditions{UID: &uid}},
+	)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// dryRunOption returns the DryRun value of create and update options.
+func dryRunOption(dryRun bool) []string {
+	if dryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
 // isReconcilerManaged returns true if the reconciler manages the provided object.
 func isReconcilerManaged(obj unstructured.Unstructured) bool {
-	val := obj.GetAnnotations()[reconcilerAnnotationKey]
-	return val == reconcilerAnnotationValue
+	val := obj.GetAnnotations()[types.ManagedAnnotationKey]
+	return val == types.ManagedAnnotationValue
 }
 
 // sameIntent checks that, barring certain ignored fields, the provided objects are semantically equal.
@@ -200,6 +347,15 @@ func fetchResourceState(typedClient dynamic.Interface, desiredResources []types.
 	return desiredToActual, nil
 }
 
+// redactError drops the details of an error, which may quote the content of the object.
+// Only the reason of API errors is kept.
+func redactError(err error) error {
+	if reason := apierrors.ReasonForError(err); reason != metav1.StatusReasonUnknown {
+		return fmt.Errorf("request failed with reason %s, details are redacted", reason)
+	}
+	return fmt.Errorf("request failed, details are redacted")
+}
+
 func invalidGVR(gvr schema.GroupVersionResource) bool {
 	if gvr.Resource == "" {
 		return true
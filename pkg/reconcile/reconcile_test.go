@@ -0,0 +1,215 @@
+package reconcile
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/vllry/cluster-reconciler/pkg/types"
+)
+
+var configMapGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+func inventoryConfigMap(name, inventory string, managed bool) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.SetUID(k8stypes.UID(name))
+	obj.SetLabels(map[string]string{types.InventoryLabelKey: inventory})
+	if managed {
+		obj.SetAnnotations(map[string]string{types.ManagedAnnotationKey: types.ManagedAnnotationValue})
+	}
+	return obj
+}
+
+func TestPruneManagedResources(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"list", "delete"}}},
+		},
+	}
+	dynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+		inventoryConfigMap("kept", "sync", true),
+		inventoryConfigMap("removed", "sync", true),
+		inventoryConfigMap("unmanaged", "sync", false),
+		inventoryConfigMap("other", "other", true),
+	)
+
+	desired := map[types.ResourceIdentifier]types.Semistructured{}
+	semi, err := types.UnstructuredToSemistructured(*inventoryConfigMap("kept", "sync", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired[semi.Identifier] = semi
+
+	results, err := PruneManagedResources(client, dynamicClient, desired, "sync", ReconcileOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || !results[0].Drifted || results[0].Identifier.NamespacedName.Name != "removed" {
+		t.Fatalf("unexpected read only results %+v", results)
+	}
+
+	results, err = PruneManagedResources(client, dynamicClient, desired, "sync", ReconcileOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || !results[0].Pruned || results[0].Err != nil {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	list, err := dynamicClient.Resource(configMapGVR).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining := map[string]bool{}
+	for _, obj := range list.Items {
+		remaining[obj.GetName()] = true
+	}
+	if len(remaining) != 3 || remaining["removed"] {
+		t.Errorf("unexpected remaining objects %v", remaining)
+	}
+}
+
+func TestDiffCluster(t *testing.T) {
+	live := inventoryConfigMap("changed", "sync", true)
+	live.Object["data"] = map[string]interface{}{"color": "pink"}
+	live.Object["status"] = map[string]interface{}{"ignored": true}
+	dynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), live, inventoryConfigMap("same", "sync", true))
+
+	desired := map[types.ResourceIdentifier]types.Semistructured{}
+	for name, data := range map[string]string{"changed": "purple", "same": "", "missing": "purple"} {
+		obj := inventoryConfigMap(name, "sync", true)
+		if data != "" {
+			obj.Object["data"] = map[string]interface{}{"color": data}
+		}
+		semi, err := types.UnstructuredToSemistructured(*obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		semi.Identifier.GroupVersionResource = configMapGVR
+		desired[semi.Identifier] = semi
+	}
+
+	diffs, err := DiffCluster(dynamicClient, func() (map[types.ResourceIdentifier]types.Semistructured, error) {
+		return desired, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	drifted := map[string]bool{}
+	for _, diff := range diffs {
+		if diff.Err != nil {
+			t.Errorf("unexpected error for %s: %v", diff.Identifier.NamespacedName, diff.Err)
+		}
+		drifted[diff.Identifier.NamespacedName.Name] = diff.Drifted
+		if name := diff.Identifier.NamespacedName.Name; (name == "missing") != (diff.Actual == nil) {
+			t.Errorf("unexpected live state of %s: %v", name, diff.Actual)
+		}
+	}
+	expected := map[string]bool{"changed": true, "same": false, "missing": true}
+	if !reflect.DeepEqual(drifted, expected) {
+		t.Errorf("expected drift %v, got %v", expected, drifted)
+	}
+}
+
+// recordingClient is a dynamic client which records the dry run option of every write, since the fake
+// dynamic client does not keep the options it is called with.
+type recordingClient struct {
+	dynamic.Interface
+	dryRuns map[string][]string
+}
+
+func (c *recordingClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &recordingResource{NamespaceableResourceInterface: c.Interface.Resource(gvr), client: c}
+}
+
+type recordingResource struct {
+	dynamic.NamespaceableResourceInterface
+	client *recordingClient
+}
+
+func (r *recordingResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return &recordingNamespacedResource{ResourceInterface: r.NamespaceableResourceInterface.Namespace(namespace), client: r.client}
+}
+
+type recordingNamespacedResource struct {
+	dynamic.ResourceInterface
+	client *recordingClient
+}
+
+func (r *recordingNamespacedResource) Create(ctx context.Context, obj *unstructured.Unstructured, options metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	r.client.dryRuns["create "+obj.GetName()] = options.DryRun
+	return r.ResourceInterface.Create(ctx, obj, options, subresources...)
+}
+
+func (r *recordingNamespacedResource) Update(ctx context.Context, obj *unstructured.Unstructured, options metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	r.client.dryRuns["update "+obj.GetName()] = options.DryRun
+	return r.ResourceInterface.Update(ctx, obj, options, subresources...)
+}
+
+func TestReconcileClusterDryRun(t *testing.T) {
+	live := inventoryConfigMap("changed", "sync", true)
+	live.Object["data"] = map[string]interface{}{"color": "pink"}
+
+	desired := map[types.ResourceIdentifier]types.Semistructured{}
+	for _, name := range []string{"changed", "missing"} {
+		obj := inventoryConfigMap(name, "sync", true)
+		obj.Object["data"] = map[string]interface{}{"color": "purple"}
+		semi, err := types.UnstructuredToSemistructured(*obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		semi.Identifier.GroupVersionResource = configMapGVR
+		desired[semi.Identifier] = semi
+	}
+	fetch := func() (map[types.ResourceIdentifier]types.Semistructured, error) {
+		return desired, nil
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		client := &recordingClient{
+			Interface: fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), live.DeepCopy()),
+			dryRuns:   map[string][]string{},
+		}
+		results, err := ReconcileCluster(fake.NewSimpleClientset(), client, fetch, ReconcileOptions{DryRun: dryRun})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, result := range results {
+			if result.Err != nil || !result.Updated {
+				t.Errorf("dry run %t: unexpected result %+v", dryRun, result)
+			}
+			// Objects created in a dry run do not exist.
+			if exists := result.Identifier.NamespacedName.Name == "changed" || !dryRun; result.Exists != exists {
+				t.Errorf("dry run %t: expected %s to exist %t", dryRun, result.Identifier.NamespacedName.Name, exists)
+			}
+		}
+
+		var expected []string
+		if dryRun {
+			expected = []string{metav1.DryRunAll}
+		}
+		for _, write := range []string{"create missing", "update changed"} {
+			options, found := client.dryRuns[write]
+			if !found {
+				t.Errorf("dry run %t: expected %s to be sent", dryRun, write)
+			} else if !reflect.DeepEqual(options, expected) {
+				t.Errorf("dry run %t: expected %s with dry run %v, got %v", dryRun, write, expected, options)
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package reconcile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffContextLines is the number of unchanged lines shown around every change of a unified diff.
+const DiffContextLines = 3
+
+// diffOp is a line of a line diff, ' ' if it is in both texts, '-' if it is only in the first one
+// and '+' if it is only in the second one.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// UnifiedDiff returns the lines of a unified diff from the text named fromName to the text named toName.
+// It returns no lines if the texts are equal.
+func UnifiedDiff(fromName, toName, from, to string) []string {
+	ops := diffLines(splitLines(from), splitLines(to))
+
+	changed := []int{}
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	// fromLine and toLine are the number of lines of each text before every op.
+	fromLine := make([]int, len(ops)+1)
+	toLine := make([]int, len(ops)+1)
+	for i, op := range ops {
+		fromLine[i+1], toLine[i+1] = fromLine[i], toLine[i]
+		if op.kind != '+' {
+			fromLine[i+1]++
+		}
+		if op.kind != '-' {
+			toLine[i+1]++
+		}
+	}
+
+	lines := []string{"--- " + fromName, "+++ " + toName}
+	for first := 0; first < len(changed); {
+		// Changes whose context overlaps are in the same hunk.
+		last := first
+		for last+1 < len(changed) && changed[last+1]-changed[last] <= 2*DiffContextLines {
+			last++
+		}
+		start := changed[first] - DiffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := changed[last] + DiffContextLines + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		lines = append(lines, fmt.Sprintf("@@ -%s +%s @@",
+			hunkRange(fromLine[start], fromLine[end]-fromLine[start]), hunkRange(toLine[start], toLine[end]-toLine[start])))
+		for _, op := range ops[start:end] {
+			lines = append(lines, string(op.kind)+op.line)
+		}
+		first = last + 1
+	}
+	return lines
+}
+
+// hunkRange formats the range of a hunk, which starts after the line before if it is empty.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// maxDiffCells bounds the size of the table of diffLines. Texts which differ in more lines are diffed as
+// a removal of the changed lines of a followed by an addition of the changed lines of b.
+const maxDiffCells = 1 << 20
+
+// diffLines returns the line diff from a to b, keeping their longest common subsequence.
+// The common prefix and suffix are trimmed first, so the quadratic table only spans the changed lines.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{kind: ' ', line: line})
+	}
+	ops = append(ops, diffChangedLines(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{kind: ' ', line: line})
+	}
+	return ops
+}
+
+// diffChangedLines returns the line diff from a to b with the table of their longest common subsequence.
+func diffChangedLines(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{kind: '-', line: line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{kind: '+', line: line})
+		}
+		return ops
+	}
+
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			ops = append(ops, diffOp{kind: '-', line: a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{kind: '-', line: a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{kind: '+', line: b[j]})
+	}
+	return ops
+}
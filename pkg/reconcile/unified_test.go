@@ -0,0 +1,78 @@
+package reconcile
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	to := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+
+	expected := []string{
+		"--- live",
+		"+++ desired",
+		"@@ -1,5 +1,5 @@",
+		" a",
+		"-b",
+		"+B",
+		" c",
+		" d",
+		" e",
+		"@@ -10,3 +10,4 @@",
+		" j",
+		" k",
+		" l",
+		"+m",
+	}
+	if lines := UnifiedDiff("live", "desired", from, to); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+
+	expected = []string{"--- live", "+++ desired", "@@ -0,0 +1,2 @@", "+a", "+b"}
+	if lines := UnifiedDiff("live", "desired", "", "a\nb\n"); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %v, got %v", expected, lines)
+	}
+
+	if lines := UnifiedDiff("live", "desired", from, from); len(lines) != 0 {
+		t.Errorf("expected no diff, got %v", lines)
+	}
+}
+
+func TestUnifiedDiffLarge(t *testing.T) {
+	var from, to []string
+	for i := 0; i < 20000; i++ {
+		from = append(from, fmt.Sprintf("line %d", i))
+	}
+	to = append(to, from...)
+	to[10000] = "changed"
+
+	expected := []string{
+		"--- live",
+		"+++ desired",
+		"@@ -9998,7 +9998,7 @@",
+		" line 9997",
+		" line 9998",
+		" line 9999",
+		"-line 10000",
+		"+changed",
+		" line 10001",
+		" line 10002",
+		" line 10003",
+	}
+	lines := UnifiedDiff("live", "desired", strings.Join(from, "\n")+"\n", strings.Join(to, "\n")+"\n")
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+
+	// Texts which differ everywhere are diffed without a table of every pair of lines.
+	for i := range to {
+		to[i] = fmt.Sprintf("other %d", i)
+	}
+	lines = UnifiedDiff("live", "desired", strings.Join(from, "\n")+"\n", strings.Join(to, "\n")+"\n")
+	if len(lines) != 3+len(from)+len(to) || lines[3] != "-line 0" || lines[3+len(from)] != "+other 0" {
+		t.Errorf("expected every line to be replaced, got %d lines", len(lines))
+	}
+}
@@ -51,3 +51,12 @@ func (p *Mapper) MappingForGVK(gvk schema.GroupVersionKind) (*meta.RESTMapping,
 
 	return mapping, nil
 }
+
+// Namespaced returns true if the kind of gvk is namespaced
+func (p *Mapper) Namespaced(gvk schema.GroupVersionKind) (bool, error) {
+	mapping, err := p.MappingForGVK(gvk)
+	if err != nil {
+		return false, err
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
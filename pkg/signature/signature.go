@@ -0,0 +1,149 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+)
+
+// ErrUnsigned is returned when a workload carries no signature.
+var ErrUnsigned = errors.New("workload is not signed")
+
+// commitSHA matches a full SHA-1 or SHA-256 git commit.
+var commitSHA = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// TrustedKeys maps a key ID to a trusted public key.
+type TrustedKeys map[string]ed25519.PublicKey
+
+// TrustedKeySource reads the trusted public keys from a ConfigMap on the spoke cluster.
+// Every entry in the ConfigMap maps a key ID to a PEM encoded ed25519 public key.
+type TrustedKeySource struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewTrustedKeySource is to create the trusted key source struct
+func NewTrustedKeySource(client kubernetes.Interface, namespace, name string) *TrustedKeySource {
+	return &TrustedKeySource{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// Keys returns the trusted keys stored in the ConfigMap.
+func (s *TrustedKeySource) Keys() (TrustedKeys, error) {
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(context.Background(), s.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	keys := TrustedKeys{}
+	for keyID, data := range cm.Data {
+		key, err := ParsePublicKey([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted key %q: %v", keyID, err)
+		}
+		keys[keyID] = key
+	}
+	return keys, nil
+}
+
+// ParsePublicKey parses a PEM encoded PKIX ed25519 public key.
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found in public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an ed25519 key")
+	}
+	return edKey, nil
+}
+
+// CanonicalWorkload returns the content of a workload which is signed, which is every field of the workload,
+// along with the defaults expanded into its manifests if there are any.
+// Manifests are re-encoded with sorted keys, so the content does not depend on how the hub serialized them.
+func CanonicalWorkload(workload multiclusterv1alpha1.WorkloadTemplate, defaults *multiclusterv1alpha1.WorkloadDefaults) ([]byte, error) {
+	canonical := *workload.DeepCopy()
+	for index, manifest := range canonical.Manifests {
+		decoder := json.NewDecoder(bytes.NewReader(manifest.Raw))
+		// Numbers are kept as written, so large integers are not rounded through float64.
+		decoder.UseNumber()
+		var content interface{}
+		if err := decoder.Decode(&content); err != nil {
+			return nil, fmt.Errorf("manifest %d is not valid json: %v", index, err)
+		}
+		if decoder.More() {
+			return nil, fmt.Errorf("manifest %d is not valid json: unexpected content after the object", index)
+		}
+		raw, err := json.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		canonical.Manifests[index].Raw = raw
+		canonical.Manifests[index].Object = nil
+	}
+	if defaults == nil {
+		return json.Marshal(canonical)
+	}
+	return json.Marshal(struct {
+		Workload multiclusterv1alpha1.WorkloadTemplate  `json:"workload"`
+		Defaults *multiclusterv1alpha1.WorkloadDefaults `json:"defaults"`
+	}{Workload: canonical, Defaults: defaults})
+}
+
+// Sign returns a detached signature over the canonical content of the workload and its defaults.
+func Sign(keyID string, privateKey ed25519.PrivateKey, workload multiclusterv1alpha1.WorkloadTemplate, defaults *multiclusterv1alpha1.WorkloadDefaults) (multiclusterv1alpha1.WorkloadSignature, error) {
+	content, err := CanonicalWorkload(workload, defaults)
+	if err != nil {
+		return multiclusterv1alpha1.WorkloadSignature{}, err
+	}
+	return multiclusterv1alpha1.WorkloadSignature{
+		KeyID:     keyID,
+		Signature: ed25519.Sign(privateKey, content),
+	}, nil
+}
+
+// Verify checks that at least one of the signatures is a valid signature over the workload and its defaults,
+// made by a trusted key. Only the URL, ref and path of a git source are signed, so its ref must be a full
+// commit SHA, which pins the content that is read.
+func Verify(trusted TrustedKeys, workload multiclusterv1alpha1.WorkloadTemplate, defaults *multiclusterv1alpha1.WorkloadDefaults, signatures []multiclusterv1alpha1.WorkloadSignature) error {
+	if len(signatures) == 0 {
+		return ErrUnsigned
+	}
+	if workload.Git != nil && !commitSHA.MatchString(workload.Git.Ref) {
+		return fmt.Errorf("git ref %q is not a full commit sha, so the signed workload could change", workload.Git.Ref)
+	}
+	content, err := CanonicalWorkload(workload, defaults)
+	if err != nil {
+		return err
+	}
+
+	for _, sig := range signatures {
+		key, found := trusted[sig.KeyID]
+		if !found {
+			continue
+		}
+		if ed25519.Verify(key, content, sig.Signature) {
+			return nil
+		}
+	}
+	return errors.New("no valid signature from a trusted key")
+}
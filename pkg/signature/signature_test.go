@@ -0,0 +1,124 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	multiclusterv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+)
+
+func TestVerify(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted := TrustedKeys{"release": publicKey}
+
+	signed := multiclusterv1alpha1.WorkloadTemplate{
+		Manifests: []multiclusterv1alpha1.Manifest{
+			{RawExtension: runtime.RawExtension{Raw: []byte(`{"kind":"ConfigMap","apiVersion":"v1","metadata":{"name":"cm"}}`)}},
+		},
+	}
+	sig, err := Sign("release", privateKey, signed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Key order does not change the canonical content.
+	reordered := multiclusterv1alpha1.WorkloadTemplate{
+		Manifests: []multiclusterv1alpha1.Manifest{
+			{RawExtension: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"}}`)}},
+		},
+	}
+	if err := Verify(trusted, reordered, nil, []multiclusterv1alpha1.WorkloadSignature{sig}); err != nil {
+		t.Errorf("expected signature to be valid, got %v", err)
+	}
+
+	tampered := multiclusterv1alpha1.WorkloadTemplate{
+		Manifests: []multiclusterv1alpha1.Manifest{
+			{RawExtension: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"other"}}`)}},
+		},
+	}
+	if err := Verify(trusted, tampered, nil, []multiclusterv1alpha1.WorkloadSignature{sig}); err == nil {
+		t.Error("expected tampered workload to fail verification")
+	}
+
+	if err := Verify(trusted, signed, nil, nil); err != ErrUnsigned {
+		t.Errorf("expected ErrUnsigned, got %v", err)
+	}
+
+	// Defaults are expanded by the agent, so they are signed along with the workload.
+	defaults := &multiclusterv1alpha1.WorkloadDefaults{Namespace: "apps"}
+	withDefaults, err := Sign("release", privateKey, signed, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(trusted, signed, defaults, []multiclusterv1alpha1.WorkloadSignature{withDefaults}); err != nil {
+		t.Errorf("expected signature over the defaults to be valid, got %v", err)
+	}
+	if err := Verify(trusted, signed, &multiclusterv1alpha1.WorkloadDefaults{Namespace: "other"}, []multiclusterv1alpha1.WorkloadSignature{withDefaults}); err == nil {
+		t.Error("expected tampered defaults to fail verification")
+	}
+	if err := Verify(trusted, signed, defaults, []multiclusterv1alpha1.WorkloadSignature{sig}); err == nil {
+		t.Error("expected added defaults to fail verification")
+	}
+
+	// Branches and tags of a git source can move, only a commit pins the signed content.
+	for ref, valid := range map[string]bool{"main": false, "v1": false, "0123456789abcdef0123456789abcdef01234567": true} {
+		withGit := *signed.DeepCopy()
+		withGit.Git = &multiclusterv1alpha1.GitSource{URL: "https://example.com/repo.git", Ref: ref}
+		gitSig, err := Sign("release", privateKey, withGit, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Verify(trusted, withGit, nil, []multiclusterv1alpha1.WorkloadSignature{gitSig}); (err == nil) != valid {
+			t.Errorf("git ref %s: expected valid %t, got %v", ref, valid, err)
+		}
+	}
+
+	untrusted := sig
+	untrusted.KeyID = "unknown"
+	if err := Verify(trusted, signed, nil, []multiclusterv1alpha1.WorkloadSignature{untrusted}); err == nil {
+		t.Error("expected signature from an untrusted key to fail verification")
+	}
+}
+
+func TestCanonicalWorkload(t *testing.T) {
+	// Every field of the workload is set, so a field which is not signed fails the round trip.
+	workload := multiclusterv1alpha1.WorkloadTemplate{
+		Manifests: []multiclusterv1alpha1.Manifest{
+			{RawExtension: runtime.RawExtension{Raw: []byte(`{"kind":"ConfigMap","apiVersion":"v1","metadata":{"name":"cm"},"data":{"replicas":9007199254740993}}`)}},
+		},
+		EncryptedManifests: []multiclusterv1alpha1.EncryptedManifest{{EncryptedKey: []byte("key"), Ciphertext: []byte("ciphertext")}},
+		Kustomization: &multiclusterv1alpha1.Kustomization{
+			Path:  "overlays/prod",
+			Files: []multiclusterv1alpha1.KustomizationFile{{Path: "kustomization.yaml", Content: "resources: []"}},
+		},
+		Git: &multiclusterv1alpha1.GitSource{URL: "https://example.com/repo.git", Ref: "main", Path: "manifests"},
+	}
+	value := reflect.ValueOf(workload)
+	for i := 0; i < value.NumField(); i++ {
+		if value.Field(i).IsZero() {
+			t.Fatalf("field %s of the workload is not set in the test", value.Type().Field(i).Name)
+		}
+	}
+
+	content, err := CanonicalWorkload(workload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := multiclusterv1alpha1.WorkloadTemplate{}
+	if err := json.Unmarshal(content, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	expected := workload.DeepCopy()
+	expected.Manifests[0].Raw = []byte(`{"apiVersion":"v1","data":{"replicas":9007199254740993},"kind":"ConfigMap","metadata":{"name":"cm"}}`)
+	if !reflect.DeepEqual(&decoded, expected) {
+		t.Errorf("expected the canonical content to hold the workload %+v, got %+v", expected, decoded)
+	}
+}
@@ -0,0 +1,124 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/vllry/cluster-reconciler/pkg/inventory"
+	"github.com/vllry/cluster-reconciler/pkg/reconcile"
+	"github.com/vllry/cluster-reconciler/pkg/restmapper"
+	"github.com/vllry/cluster-reconciler/pkg/types"
+)
+
+// Syncer reconciles a cluster with an inventory, without a hub.
+type Syncer struct {
+	Client        kubernetes.Interface
+	DynamicClient dynamic.Interface
+	RestMapper    *restmapper.Mapper
+	Log           logr.Logger
+	// Source reads the objects of the inventory.
+	Source inventory.Source
+	// Inventory names the inventory, objects are labeled with it so they can be pruned.
+	Inventory string
+	// Prune deletes the objects of the inventory which are no longer fetched.
+	Prune bool
+	// Options changes how the cluster is written.
+	Options reconcile.ReconcileOptions
+}
+
+// Run syncs the cluster every interval, and whenever the source changes, until stopCh is closed.
+func (s *Syncer) Run(interval time.Duration, stopCh <-chan struct{}) {
+	changed := s.Source.Watch(stopCh)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.SyncOnce(); err != nil {
+			s.Log.Error(err, "unable to sync", "inventory", s.Inventory)
+		}
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		case _, ok := <-changed:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// SyncOnce reconciles the cluster with the objects of the inventory, and prunes removed objects.
+// Failures of single objects are returned in the results, and logged.
+func (s *Syncer) SyncOnce() ([]reconcile.ReconcileResult, error) {
+	var desired map[types.ResourceIdentifier]types.Semistructured
+	resolve := inventory.Resolver(s.RestMapper)
+	fetch := inventory.FetchFunc(s.Source, func(obj unstructured.Unstructured) types.Semistructured {
+		return resolve(s.label(obj))
+	})
+	fetchFunc := func() (map[types.ResourceIdentifier]types.Semistructured, error) {
+		var err error
+		desired, err = fetch()
+		return desired, err
+	}
+
+	results, err := reconcile.ReconcileCluster(s.Client, s.DynamicClient, fetchFunc, s.Options)
+	if err != nil {
+		return results, err
+	}
+	if s.Prune && FetchFailed(desired) {
+		// An object which failed to be read may still be in the inventory, it must not be deleted.
+		s.Log.Info("skipping prune, some objects of the inventory could not be read", "inventory", s.Inventory)
+	} else if s.Prune {
+		pruned, err := reconcile.PruneManagedResources(s.Client, s.DynamicClient, desired, s.Inventory, s.Options)
+		results = append(results, pruned...)
+		if err != nil {
+			return results, err
+		}
+	}
+
+	for _, result := range results {
+		id := result.Identifier
+		log := s.Log.WithValues("kind", id.GroupVersionKind.Kind, "namespace", id.NamespacedName.Namespace, "name", id.NamespacedName.Name)
+		switch {
+		case result.Err != nil:
+			log.Error(result.Err, "unable to sync object", "source", id.Source, "ordinal", id.Ordinal, "item", id.Item)
+		case result.Pruned:
+			log.Info("pruned object")
+		case result.Updated:
+			log.Info("synced object", "diff", result.Diff)
+		}
+	}
+	return results, nil
+}
+
+// FetchFailed returns true if any desired object failed to be read or resolved.
+func FetchFailed(desired map[types.ResourceIdentifier]types.Semistructured) bool {
+	for _, obj := range desired {
+		if obj.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// label marks an object as managed by the inventory.
+func (s *Syncer) label(obj unstructured.Unstructured) unstructured.Unstructured {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[types.InventoryLabelKey] = s.Inventory
+	obj.SetLabels(labels)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[types.ManagedAnnotationKey] = types.ManagedAnnotationValue
+	obj.SetAnnotations(annotations)
+	return obj
+}
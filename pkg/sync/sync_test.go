@@ -0,0 +1,125 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/vllry/cluster-reconciler/pkg/inventory"
+	"github.com/vllry/cluster-reconciler/pkg/restmapper"
+	"github.com/vllry/cluster-reconciler/pkg/types"
+)
+
+var configMapGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+// newFakeSyncer returns a syncer of the "sync" inventory reading source, against a cluster holding objs.
+func newFakeSyncer(source inventory.Source, objs ...runtime.Object) *Syncer {
+	client := fake.NewSimpleClientset()
+	client.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"list", "delete"}}},
+		},
+	}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	return &Syncer{
+		Client:        client,
+		DynamicClient: fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), objs...),
+		RestMapper:    &restmapper.Mapper{Mapper: mapper},
+		Log:           ctrl.Log.WithName("sync"),
+		Source:        source,
+		Inventory:     "sync",
+		Prune:         true,
+	}
+}
+
+// inventoryConfigMap returns a ConfigMap synced by the "sync" inventory.
+func inventoryConfigMap(name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.SetUID(k8stypes.UID(name))
+	obj.SetLabels(map[string]string{types.InventoryLabelKey: "sync"})
+	obj.SetAnnotations(map[string]string{types.ManagedAnnotationKey: types.ManagedAnnotationValue})
+	return obj
+}
+
+func TestSyncOnce(t *testing.T) {
+	source := &inventory.InlineSource{Content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n"}
+	syncer := newFakeSyncer(source, inventoryConfigMap("removed"))
+
+	results, err := syncer.SyncOnce()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pruned := 0
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("unexpected error for %s: %v", result.Identifier.NamespacedName, result.Err)
+		}
+		if result.Pruned {
+			pruned++
+		}
+	}
+	if pruned != 1 {
+		t.Errorf("expected the removed object to be pruned, got %+v", results)
+	}
+
+	// The object without a namespace is synced in the default namespace, and labeled with the inventory.
+	synced, err := syncer.DynamicClient.Resource(configMapGVR).Namespace("default").Get(context.Background(), "app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the object to be synced: %v", err)
+	}
+	if synced.GetLabels()[types.InventoryLabelKey] != "sync" || synced.GetAnnotations()[types.ManagedAnnotationKey] != types.ManagedAnnotationValue {
+		t.Errorf("expected the object to be labeled with the inventory, got %v", synced.Object)
+	}
+	if _, err := syncer.DynamicClient.Resource(configMapGVR).Namespace("default").Get(context.Background(), "removed", metav1.GetOptions{}); err == nil {
+		t.Errorf("expected the removed object to be deleted")
+	}
+}
+
+func TestSyncOnceSkipsPruneOnFailedObjects(t *testing.T) {
+	registry := inventory.NewRegistry()
+	if err := registry.Register("inline", &inventory.InlineSource{Content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n"}); err != nil {
+		t.Fatal(err)
+	}
+	// The key which fails to parse may hold objects of the inventory.
+	manifests := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "manifests"},
+		Data:       map[string]string{"broken.yaml": "kind: [ConfigMap"},
+	}
+	if err := registry.Register("configmap", &inventory.ConfigMapSource{Client: fake.NewSimpleClientset(manifests), Namespace: "default", Name: "manifests"}); err != nil {
+		t.Fatal(err)
+	}
+	syncer := newFakeSyncer(registry, inventoryConfigMap("kept"))
+
+	results, err := syncer.SyncOnce()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, result := range results {
+		if result.Pruned {
+			t.Errorf("expected nothing to be pruned, got %+v", result)
+		}
+	}
+	if _, err := syncer.DynamicClient.Resource(configMapGVR).Namespace("default").Get(context.Background(), "kept", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the object to be kept: %v", err)
+	}
+	if _, err := syncer.DynamicClient.Resource(configMapGVR).Namespace("default").Get(context.Background(), "app", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the readable object to be synced: %v", err)
+	}
+}
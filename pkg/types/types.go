@@ -10,9 +10,26 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 )
 
+// ManagedAnnotationKey is the annotation marking objects which are managed by the reconciler.
+const ManagedAnnotationKey = "cluster-reconciler-managed"
+
+// ManagedAnnotationValue is the value of ManagedAnnotationKey on managed objects.
+const ManagedAnnotationValue = "true"
+
+// InventoryLabelKey labels managed objects with the name of their inventory, so objects which are
+// removed from the inventory can be pruned.
+const InventoryLabelKey = "cluster-reconciler.x-k8s.io/inventory"
+
 // ResourceIdentifier provides the identifiers needed to interact with any arbitrary object.
+// Objects which could not be resolved are identified by where they were read from, with Source, Ordinal and Item.
 type ResourceIdentifier struct {
-	Ordinal              int
+	// Source is the part of the source holding the object, it is empty for the manifests of a work.
+	Source string
+	// Ordinal is the index of the manifest holding the object in Source.
+	Ordinal int
+	// Item is the index of the object among the objects of its manifest, such as the items of a List.
+	Item int
+
 	GroupVersionKind     schema.GroupVersionKind
 	GroupVersionResource schema.GroupVersionResource
 	NamespacedName       types.NamespacedName
@@ -24,6 +41,10 @@ type Semistructured struct {
 	Identifier ResourceIdentifier
 	// Unstructured is the full Unstructured object.
 	Unstructured unstructured.Unstructured
+	// Err is set when the object could not be resolved, and explains why.
+	Err error
+	// Sensitive marks objects whose content must not be surfaced, such as in error messages.
+	Sensitive bool
 }
 
 // UnstructuredToSemistructured takes an Unstructured object,
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+
+	workv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+	"github.com/vllry/cluster-reconciler/pkg/helpers"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// workConditionColumns are the conditions of works shown as columns.
+var workConditionColumns = []string{"Applied", "Available", "Degraded", "InSync"}
+
+// workStatus is the status of a work, as printed by the status command.
+type workStatus struct {
+	Namespace          string `json:"namespace"`
+	Name               string `json:"name"`
+	Generation         int64  `json:"generation"`
+	ObservedGeneration int64  `json:"observedGeneration"`
+	// Conditions maps the type of every condition of the work to its status.
+	Conditions map[string]metav1.ConditionStatus `json:"conditions"`
+	Manifests  []manifestStatus                  `json:"manifests"`
+	// Deleted is set when a watched work was deleted.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// manifestStatus is the status of a manifest of a work.
+type manifestStatus struct {
+	Ordinal   int    `json:"ordinal"`
+	Source    string `json:"source,omitempty"`
+	Item      int    `json:"item,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Applied is the status of the Applied condition, or of the DryRun condition of dry run works.
+	Applied metav1.ConditionStatus `json:"applied"`
+	// Healthy is False if the manifest is degraded, unavailable or drifted, and Unknown if it is not reported.
+	Healthy metav1.ConditionStatus `json:"healthy"`
+	Message string                 `json:"message,omitempty"`
+}
+
+// runStatus prints the status of the works on the hub, and of their manifests.
+func runStatus(args []string) error {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	hubKubeconfig := flags.String("hub-kubeconfig", "", "The kubeconfig of the hub. If it is empty, $KUBECONFIG, ~/.kube/config or the in-cluster config is used")
+	namespace := flags.String("namespace", "", "The cluster namespace of the works. Works of every cluster namespace are listed if it is empty")
+	workName := flags.String("work", "", "The name of a work in --namespace, whose manifests are listed")
+	output := flags.String("output", outputTable, "The output format, one of table or json")
+	watchWorks := flags.Bool("watch", false, "Print the status of works again whenever they change")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *output != outputTable && *output != outputJSON {
+		return fmt.Errorf("--output must be table or json")
+	}
+	if *workName != "" && *namespace == "" {
+		return fmt.Errorf("--namespace is required with --work")
+	}
+
+	config, err := loadKubeconfig(*hubKubeconfig)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("unable to create hub dynamic client: %v", err)
+	}
+	works := dynamicClient.Resource(workv1alpha1.GroupVersion.WithResource("works")).Namespace(*namespace)
+
+	listOptions := metav1.ListOptions{}
+	if *workName != "" {
+		listOptions.FieldSelector = "metadata.name=" + *workName
+	}
+	statuses, resourceVersion, err := listWorkStatuses(works, listOptions)
+	if err != nil {
+		return err
+	}
+	if *workName != "" && len(statuses) == 0 {
+		return fmt.Errorf("work %s/%s not found", *namespace, *workName)
+	}
+	printer := &statusPrinter{out: os.Stdout, output: *output, manifests: *workName != ""}
+	if err := printer.print(statuses); err != nil {
+		return err
+	}
+	if !*watchWorks {
+		return nil
+	}
+
+	for {
+		if err := watchWorkStatuses(works, listOptions, resourceVersion, printer); err != nil {
+			return err
+		}
+		// The watched resource version expired, works are listed again to catch up.
+		statuses, resourceVersion, err = listWorkStatuses(works, listOptions)
+		if err != nil {
+			return err
+		}
+		if err := printer.print(statuses); err != nil {
+			return err
+		}
+	}
+}
+
+// listWorkStatuses lists works sorted by namespace and name, and returns the resource version of the list.
+func listWorkStatuses(works dynamic.ResourceInterface, listOptions metav1.ListOptions) ([]workStatus, string, error) {
+	list, err := works.List(context.Background(), listOptions)
+	if err != nil {
+		return nil, "", err
+	}
+	statuses := []workStatus{}
+	for i := range list.Items {
+		status, err := toWorkStatus(&list.Items[i])
+		if err != nil {
+			return nil, "", err
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Namespace != statuses[j].Namespace {
+			return statuses[i].Namespace < statuses[j].Namespace
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses, list.GetResourceVersion(), nil
+}
+
+// watchWorkStatuses prints works as they change from resourceVersion. Watches closed by the apiserver are
+// restarted, it returns without error once resourceVersion expired and works must be listed again.
+func watchWorkStatuses(works dynamic.ResourceInterface, listOptions metav1.ListOptions, resourceVersion string, printer *statusPrinter) error {
+	watcher, err := watchtools.NewRetryWatcher(resourceVersion, &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = listOptions.FieldSelector
+			return works.Watch(context.Background(), options)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for event := range watcher.ResultChan() {
+		if event.Type == watch.Error {
+			if err := apierrors.FromObject(event.Object); apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+				return nil
+			}
+			return fmt.Errorf("watch failed: %v", apierrors.FromObject(event.Object))
+		}
+		obj, ok := event.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		status, err := toWorkStatus(obj)
+		if err != nil {
+			return err
+		}
+		status.Deleted = event.Type == watch.Deleted
+		if err := printer.print([]workStatus{status}); err != nil {
+			return err
+		}
+	}
+	// The retry watcher only stops once it cannot resume the watch.
+	return nil
+}
+
+// toWorkStatus summarizes the status of a work.
+func toWorkStatus(obj *unstructured.Unstructured) (workStatus, error) {
+	work := &workv1alpha1.Work{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, work); err != nil {
+		return workStatus{}, fmt.Errorf("invalid work %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	status := workStatus{
+		Namespace:          work.Namespace,
+		Name:               work.Name,
+		Generation:         work.Generation,
+		ObservedGeneration: work.Status.ObservedGeneration,
+		Conditions:         map[string]metav1.ConditionStatus{},
+		Manifests:          []manifestStatus{},
+	}
+	for _, condition := range work.Status.Conditions {
+		status.Conditions[condition.Type] = condition.Status
+	}
+	for _, manifestCondition := range work.Status.ManifestConditions {
+		status.Manifests = append(status.Manifests, toManifestStatus(manifestCondition))
+	}
+	return status, nil
+}
+
+func toManifestStatus(manifestCondition workv1alpha1.ManifestCondition) manifestStatus {
+	id := manifestCondition.Identifier
+	conditions := manifestCondition.Conditions
+	status := manifestStatus{
+		Ordinal:   id.Ordinal,
+		Source:    id.Source,
+		Item:      id.Item,
+		Group:     id.Group,
+		Version:   id.Version,
+		Kind:      id.Kind,
+		Namespace: id.Namespace,
+		Name:      id.Name,
+		Applied:   metav1.ConditionUnknown,
+		Healthy:   metav1.ConditionUnknown,
+	}
+
+	applied := helpers.FindWorkCondition(conditions, "Applied")
+	if applied == nil {
+		applied = helpers.FindWorkCondition(conditions, "DryRun")
+	}
+	if applied != nil {
+		status.Applied = applied.Status
+		status.Message = applied.Message
+	}
+
+	for _, conditionType := range []string{"Available", "InSync"} {
+		if condition := helpers.FindWorkCondition(conditions, conditionType); condition != nil {
+			status.Healthy = condition.Status
+			if condition.Status != metav1.ConditionTrue {
+				status.Message = condition.Message
+				break
+			}
+		}
+	}
+	if degraded := helpers.FindWorkCondition(conditions, "Degraded"); helpers.IsConditionTrue(degraded) {
+		status.Healthy = metav1.ConditionFalse
+		status.Message = degraded.Message
+	}
+	// Failures to apply explain the other conditions.
+	if applied != nil && applied.Status == metav1.ConditionFalse {
+		status.Message = applied.Message
+	}
+	return status
+}
+
+// statusPrinter prints works, or the manifests of works, as a table or as JSON.
+// In JSON, every work is printed on its own line so watched works can be streamed.
+type statusPrinter struct {
+	out    io.Writer
+	output string
+	// manifests prints a row for every manifest of works, rather than one for every work.
+	manifests bool
+
+	// widths are the widths of the table columns, they are kept across prints so watched rows stay aligned.
+	widths []int
+}
+
+func (p *statusPrinter) print(statuses []workStatus) error {
+	if p.output == outputJSON {
+		encoder := json.NewEncoder(p.out)
+		for _, status := range statuses {
+			if err := encoder.Encode(status); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rows := [][]string{}
+	if p.widths == nil {
+		rows = append(rows, p.header())
+	}
+	for _, status := range statuses {
+		if p.manifests {
+			rows = append(rows, manifestRows(status)...)
+		} else {
+			rows = append(rows, workRow(status))
+		}
+	}
+	for _, row := range rows {
+		for i, column := range row {
+			if i == len(p.widths) {
+				p.widths = append(p.widths, 0)
+			}
+			if len(column) > p.widths[i] {
+				p.widths[i] = len(column)
+			}
+		}
+	}
+	for _, row := range rows {
+		line := ""
+		for i, column := range row {
+			if i == len(row)-1 {
+				line += column
+			} else {
+				line += fmt.Sprintf("%-*s", p.widths[i]+2, column)
+			}
+		}
+		if _, err := fmt.Fprintln(p.out, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *statusPrinter) header() []string {
+	if p.manifests {
+		return []string{"WORK", "ORDINAL", "KIND", "NAME", "APPLIED", "HEALTHY", "MESSAGE"}
+	}
+	header := []string{"NAMESPACE", "NAME"}
+	for _, conditionType := range workConditionColumns {
+		header = append(header, strings.ToUpper(conditionType))
+	}
+	return append(header, "MANIFESTS", "GENERATION")
+}
+
+// workRow returns the columns of a work, deleted works only keep their name.
+func workRow(status workStatus) []string {
+	columns := []string{status.Namespace, status.Name}
+	if status.Deleted {
+		for range workConditionColumns {
+			columns = append(columns, "-")
+		}
+		return append(columns, "-", "<deleted>")
+	}
+	for _, conditionType := range workConditionColumns {
+		columns = append(columns, orNone(string(status.Conditions[conditionType])))
+	}
+	generation := fmt.Sprintf("%d", status.Generation)
+	if status.ObservedGeneration != status.Generation {
+		generation = fmt.Sprintf("%d (observed %d)", status.Generation, status.ObservedGeneration)
+	}
+	return append(columns, fmt.Sprintf("%d", len(status.Manifests)), generation)
+}
+
+// manifestRows returns the columns of every manifest of a work, a deleted work is a single row.
+func manifestRows(status workStatus) [][]string {
+	work := status.Namespace + "/" + status.Name
+	if status.Deleted {
+		return [][]string{{work, "-", "-", "-", "-", "-", "work deleted"}}
+	}
+	rows := [][]string{}
+	for _, manifest := range status.Manifests {
+		name := manifest.Name
+		if manifest.Namespace != "" {
+			name = manifest.Namespace + "/" + name
+		}
+		rows = append(rows, []string{work, manifestPosition(manifest), orNone(manifest.Kind), orNone(name),
+			string(manifest.Applied), string(manifest.Healthy), firstLine(manifest.Message)})
+	}
+	return rows
+}
+
+// manifestPosition describes where a manifest is in the workload, as source/ordinal[item].
+func manifestPosition(manifest manifestStatus) string {
+	position := fmt.Sprintf("%d", manifest.Ordinal)
+	if manifest.Source != "" {
+		position = manifest.Source + "/" + position
+	}
+	if manifest.Item > 0 {
+		position = fmt.Sprintf("%s[%d]", position, manifest.Item)
+	}
+	return position
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+// firstLine keeps the table aligned with multi-line messages.
+func firstLine(message string) string {
+	if i := strings.Index(message, "\n"); i >= 0 {
+		return message[:i] + "..."
+	}
+	return message
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workv1alpha1 "github.com/vllry/cluster-reconciler/pkg/api/v1alpha1"
+)
+
+func TestToManifestStatus(t *testing.T) {
+	identifier := workv1alpha1.ResourceIdentifier{Ordinal: 1, Version: "v1", Kind: "ConfigMap", Namespace: "default", Name: "app"}
+	cases := []struct {
+		name       string
+		conditions []workv1alpha1.StatusCondition
+		expected   manifestStatus
+	}{
+		{
+			name:     "not reported",
+			expected: manifestStatus{Applied: metav1.ConditionUnknown, Healthy: metav1.ConditionUnknown},
+		},
+		{
+			name: "applied and available",
+			conditions: []workv1alpha1.StatusCondition{
+				{Type: "Applied", Status: metav1.ConditionTrue, Message: "Apply manifest complete"},
+				{Type: "Available", Status: metav1.ConditionTrue, Message: "Resource exists"},
+			},
+			expected: manifestStatus{Applied: metav1.ConditionTrue, Healthy: metav1.ConditionTrue, Message: "Apply manifest complete"},
+		},
+		{
+			name: "dry run",
+			conditions: []workv1alpha1.StatusCondition{
+				{Type: "DryRun", Status: metav1.ConditionTrue, Message: "The manifest would be applied"},
+			},
+			expected: manifestStatus{Applied: metav1.ConditionTrue, Healthy: metav1.ConditionUnknown, Message: "The manifest would be applied"},
+		},
+		{
+			name: "drifted",
+			conditions: []workv1alpha1.StatusCondition{
+				{Type: "Applied", Status: metav1.ConditionTrue},
+				{Type: "Available", Status: metav1.ConditionTrue},
+				{Type: "InSync", Status: metav1.ConditionFalse, Message: "data.key changed"},
+			},
+			expected: manifestStatus{Applied: metav1.ConditionTrue, Healthy: metav1.ConditionFalse, Message: "data.key changed"},
+		},
+		{
+			name: "degraded",
+			conditions: []workv1alpha1.StatusCondition{
+				{Type: "Applied", Status: metav1.ConditionTrue},
+				{Type: "Available", Status: metav1.ConditionTrue},
+				{Type: "Degraded", Status: metav1.ConditionTrue, Message: "failing for 10m"},
+			},
+			expected: manifestStatus{Applied: metav1.ConditionTrue, Healthy: metav1.ConditionFalse, Message: "failing for 10m"},
+		},
+		{
+			name: "failed to apply",
+			conditions: []workv1alpha1.StatusCondition{
+				{Type: "Applied", Status: metav1.ConditionFalse, Message: "forbidden"},
+				{Type: "Available", Status: metav1.ConditionFalse, Message: "Resource does not exist"},
+			},
+			expected: manifestStatus{Applied: metav1.ConditionFalse, Healthy: metav1.ConditionFalse, Message: "forbidden"},
+		},
+	}
+
+	for _, c := range cases {
+		c.expected.Ordinal, c.expected.Version, c.expected.Kind, c.expected.Namespace, c.expected.Name = 1, "v1", "ConfigMap", "default", "app"
+		status := toManifestStatus(workv1alpha1.ManifestCondition{Identifier: identifier, Conditions: c.conditions})
+		if !reflect.DeepEqual(status, c.expected) {
+			t.Errorf("%s: expected %+v, got %+v", c.name, c.expected, status)
+		}
+	}
+}
+
+func TestPrintWorkStatuses(t *testing.T) {
+	app := workStatus{
+		Namespace:          "cluster-east",
+		Name:               "app",
+		Generation:         2,
+		ObservedGeneration: 1,
+		Conditions:         map[string]metav1.ConditionStatus{"Applied": metav1.ConditionTrue},
+		Manifests: []manifestStatus{{
+			Ordinal: 0, Version: "v1", Kind: "ConfigMap", Namespace: "default", Name: "app",
+			Applied: metav1.ConditionFalse, Healthy: metav1.ConditionUnknown, Message: "forbidden\nby policy",
+		}, {
+			Ordinal: 1, Source: "encryptedManifests", Item: 2,
+			Applied: metav1.ConditionFalse, Healthy: metav1.ConditionFalse, Message: "missing object name",
+		}},
+	}
+	deleted := workStatus{Namespace: "cluster-east", Name: "a", Deleted: true}
+
+	cases := []struct {
+		name      string
+		output    string
+		manifests bool
+		// prints are printed in turn by the same printer, like a watch.
+		prints   [][]workStatus
+		expected string
+	}{
+		{
+			name:   "works",
+			output: outputTable,
+			prints: [][]workStatus{{app}, {deleted}},
+			expected: "" +
+				"NAMESPACE     NAME  APPLIED  AVAILABLE  DEGRADED  INSYNC  MANIFESTS  GENERATION\n" +
+				"cluster-east  app   True     <none>     <none>    <none>  2          2 (observed 1)\n" +
+				"cluster-east  a     -        -          -         -       -          <deleted>\n",
+		},
+		{
+			name:      "manifests",
+			output:    outputTable,
+			manifests: true,
+			prints:    [][]workStatus{{app}, {deleted}},
+			expected: "" +
+				"WORK              ORDINAL                  KIND       NAME         APPLIED  HEALTHY  MESSAGE\n" +
+				"cluster-east/app  0                        ConfigMap  default/app  False    Unknown  forbidden...\n" +
+				"cluster-east/app  encryptedManifests/1[2]  <none>     <none>       False    False    missing object name\n" +
+				"cluster-east/a    -                        -          -            -        -        work deleted\n",
+		},
+		{
+			name:     "json",
+			output:   outputJSON,
+			prints:   [][]workStatus{{deleted}},
+			expected: `{"namespace":"cluster-east","name":"a","generation":0,"observedGeneration":0,"conditions":null,"manifests":null,"deleted":true}` + "\n",
+		},
+	}
+
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		printer := &statusPrinter{out: out, output: c.output, manifests: c.manifests}
+		for _, statuses := range c.prints {
+			if err := printer.print(statuses); err != nil {
+				t.Fatalf("%s: unexpected error: %v", c.name, err)
+			}
+		}
+		if out.String() != c.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", c.name, c.expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/discovery"
+	cacheddiscovery "k8s.io/client-go/discovery/cached"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/vllry/cluster-reconciler/pkg/inventory"
+	"github.com/vllry/cluster-reconciler/pkg/reconcile"
+	"github.com/vllry/cluster-reconciler/pkg/restmapper"
+	"github.com/vllry/cluster-reconciler/pkg/sync"
+)
+
+// runSync reconciles a cluster with the manifests of a local directory, or of a directory of a git repository,
+// and of a ConfigMap of the cluster, without a hub.
+func runSync(args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	dir := flags.String("dir", "", "The directory of the YAML or JSON manifests to reconcile the cluster with, or - to read them from stdin. It is relative to the root of the repository with --git-url")
+	gitURL := flags.String("git-url", "", "The URL of a git repository to read the manifests from, such as file:///repos/manifests.git")
+	gitRef := flags.String("git-ref", "", "The branch, tag or commit of the git repository. The default branch is used if it is empty")
+	gitPath := flags.String("git-path", inventory.DefaultGitBinaryPath, "The git binary used to read the git repository")
+	configMap := flags.String("configmap", "", "A ConfigMap of the cluster, as namespace/name, whose values hold more manifests to reconcile the cluster with")
+	kubeconfig := flags.String("kubeconfig", "", "The kubeconfig of the cluster. If it is empty, $KUBECONFIG, ~/.kube/config or the in-cluster config is used")
+	inventoryName := flags.String("inventory", "sync", "The name of the inventory, objects are labeled with it so removed objects can be pruned")
+	interval := flags.Duration("interval", 30*time.Second, "The interval between two syncs")
+	once := flags.Bool("once", false, "Sync once and exit, with an error if any object failed")
+	prune := flags.Bool("prune", true, "Delete the objects of the inventory which were removed from the directory")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *dir == "" && *gitURL == "" && *configMap == "" {
+		return fmt.Errorf("--dir is required")
+	}
+
+	config, err := loadKubeconfig(*kubeconfig)
+	if err != nil {
+		return err
+	}
+	registry := inventory.NewRegistry()
+	syncer, err := newSyncer(config, *inventoryName, registry)
+	if err != nil {
+		return err
+	}
+	syncer.Prune = *prune
+
+	switch {
+	case *gitURL != "":
+		repository := inventory.NewGitRepository(*gitURL, *gitPath)
+		defer repository.Close()
+		err = registry.Register("git", &inventory.GitSource{
+			Repository: repository,
+			Ref:        *gitRef,
+			Path:       *dir,
+			Log:        ctrl.Log.WithName("git"),
+		})
+	case *dir == "-":
+		err = registry.Register("stdin", &inventory.ReaderSource{Reader: os.Stdin})
+	case *dir != "":
+		err = registry.Register("dir", &inventory.DirectorySource{Path: *dir})
+	}
+	if err != nil {
+		return err
+	}
+	if *configMap != "" {
+		parts := strings.Split(*configMap, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("--configmap must be namespace/name, got %q", *configMap)
+		}
+		if err := registry.Register("configmap", &inventory.ConfigMapSource{Client: syncer.Client, Namespace: parts[0], Name: parts[1]}); err != nil {
+			return err
+		}
+	}
+
+	if *once {
+		results, err := syncer.SyncOnce()
+		if err != nil {
+			return err
+		}
+		if failed := countFailed(results); failed > 0 {
+			return fmt.Errorf("failed to sync %d objects", failed)
+		}
+		return nil
+	}
+
+	setupLog.Info("syncing", "dir", *dir, "gitURL", *gitURL, "configMap", *configMap, "inventory", *inventoryName, "interval", *interval)
+	stopCh := ctrl.SetupSignalHandler()
+	// Kinds of CRDs installed by a sync are mapped once the mapper is refreshed.
+	syncer.RestMapper.Run(stopCh)
+	syncer.Run(*interval, stopCh)
+	return nil
+}
+
+// loadKubeconfig loads the kubeconfig at path, or the default kubeconfig if path is empty.
+func loadKubeconfig(path string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = path
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
+
+// newSyncer creates a syncer reconciling the cluster of config with the objects of source.
+func newSyncer(config *rest.Config, inventoryName string, source inventory.Source) (*sync.Syncer, error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create kube client: %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create dynamic client: %v", err)
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create discovery client: %v", err)
+	}
+
+	return &sync.Syncer{
+		Client:        client,
+		DynamicClient: dynamicClient,
+		RestMapper:    restmapper.NewMapper(cacheddiscovery.NewMemCacheClient(discoveryClient)),
+		Log:           ctrl.Log.WithName("sync"),
+		Source:        source,
+		Inventory:     inventoryName,
+	}, nil
+}
+
+// countFailed returns the number of objects which failed to be synced.
+func countFailed(results []reconcile.ReconcileResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}